@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wslcd/pkg/wslpath"
+)
+
+// bookmark is a named directory saved with --bookmark and resolved with @name.
+type bookmark struct {
+	name string
+	path string
+}
+
+// bookmarksFile returns the location of the bookmarks file, bookmarks in configDir.
+func bookmarksFile(home string) (string, error) {
+	dir, err := configDir(home)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bookmarks"), nil
+}
+
+// loadBookmarks reads the name=path lines of the bookmarks file. A missing file has no bookmarks.
+func loadBookmarks(home string) ([]bookmark, error) {
+	path, err := bookmarksFile(home)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read bookmarks: %v", err)
+	}
+	defer f.Close()
+
+	var bms []bookmark
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		name, p, ok := strings.Cut(sc.Text(), "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		bms = append(bms, bookmark{name: strings.TrimSpace(name), path: strings.TrimSpace(p)})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read bookmarks: %v", err)
+	}
+	return bms, nil
+}
+
+// saveBookmark records path under name, replacing any existing bookmark with that name.
+func saveBookmark(home, name, path string) error {
+	if name == "" || strings.ContainsAny(name, "=/ \t") {
+		return fmt.Errorf("invalid bookmark name: %q", name)
+	}
+	bms, err := loadBookmarks(home)
+	if err != nil {
+		return err
+	}
+	file, _ := bookmarksFile(home)
+
+	var b strings.Builder
+	replaced := false
+	for _, bm := range bms {
+		if bm.name == name {
+			bm.path, replaced = path, true
+		}
+		fmt.Fprintf(&b, "%s=%s\n", bm.name, bm.path)
+	}
+	if !replaced {
+		fmt.Fprintf(&b, "%s=%s\n", name, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("cannot save bookmark: %v", err)
+	}
+	if err := os.WriteFile(file, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("cannot save bookmark: %v", err)
+	}
+	return nil
+}
+
+// resolveBookmark returns the directory saved under name, which must still exist.
+func resolveBookmark(name, home string) (string, error) {
+	bms, err := loadBookmarks(home)
+	if err != nil {
+		return "", err
+	}
+	for _, bm := range bms {
+		if bm.name != name {
+			continue
+		}
+		tracef(1, "bookmark @%s -> %s", name, bm.path)
+		info, err := os.Stat(bm.path)
+		if err != nil {
+			return "", fmt.Errorf("bookmark @%s is stale: %w: %s", name, wslpath.ErrNotFound, bm.path)
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("bookmark @%s is stale: %w: %s", name, wslpath.ErrNotDirectory, bm.path)
+		}
+		return bm.path, nil
+	}
+	return "", fmt.Errorf("no such bookmark: @%s", name)
+}
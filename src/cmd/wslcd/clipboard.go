@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// clipboardCommand reads the Windows clipboard as text through WSL interop.
+var clipboardCommand = []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"}
+
+// readClipboard returns the text on the Windows clipboard for --clip, without its CRLF line endings and
+// surrounding whitespace. Quotes, as in Explorer's "Copy as path", are left for the library to remove.
+func readClipboard() (string, error) {
+	exe, err := exec.LookPath(clipboardCommand[0])
+	if err != nil {
+		return "", fmt.Errorf("cannot read the clipboard: %s not found\nHint: --clip needs WSL interop with Windows; paste the path instead", clipboardCommand[0])
+	}
+	out, err := exec.Command(exe, clipboardCommand[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot read the clipboard: %s: %v", strings.Join(clipboardCommand, " "), err)
+	}
+	s := strings.TrimSpace(strings.ReplaceAll(string(out), "\r\n", "\n"))
+	if s == "" {
+		return "", errors.New("no path on the clipboard")
+	}
+	tracef(1, "clipboard: %q", s)
+	return s, nil
+}
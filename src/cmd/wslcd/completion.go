@@ -0,0 +1,120 @@
+package main
+
+import "fmt"
+
+// wrapperScripts hold, for each supported shell, the function that runs wslcd and changes to the
+// directory it prints. A process cannot change its parent shell's directory, so this is how wslcd is used.
+var wrapperScripts = map[string]string{
+	"bash": `wslcd() {
+  local target
+  # 'command' forces using the external binary, not this function
+  if ! target="$(command wslcd "$@")"; then
+    return 1
+  fi
+  [ -z "$target" ] && return 1
+  cd -- "$target"
+}
+`,
+	"zsh": `wslcd() {
+  local target
+  # 'command' forces using the external binary, not this function
+  target="$(command wslcd "$@")" || return 1
+  [ -z "$target" ] && return 1
+  cd -- "$target"
+}
+`,
+	"fish": `function wslcd
+    # 'command' forces using the external binary, not this function
+    set -l target (command wslcd $argv); or return 1
+    test -n "$target"; or return 1
+    cd -- $target
+end
+`,
+	// wsl.exe -e runs wslcd without a Linux shell, so backslashes in the arguments arrive intact.
+	// The result is converted back to a Windows path (\\wsl$\... outside the drives) for Set-Location.
+	"powershell": `function wslcd {
+    $target = wsl.exe -e wslcd @args
+    if ($LASTEXITCODE -ne 0 -or -not $target) { return }
+    $win = wsl.exe -e wslcd -w -- $target
+    if ($LASTEXITCODE -ne 0 -or -not $win) { return }
+    Set-Location -LiteralPath $win
+}
+`,
+}
+
+// completionScripts hold the tab completion for each supported shell, loaded after its wrapper.
+// Windows-style words are completed by calling back into `wslcd --complete-path`; anything
+// else falls back to the shell's own directory completion.
+var completionScripts = map[string]string{
+	"bash": `
+_wslcd_complete() {
+  # Take the word from the raw line: COMP_WORDS splits drive letters at ':'.
+  local line="${COMP_LINE:0:COMP_POINT}"
+  local cur="${line##*[[:space:]]}"
+  cur="${cur#[\"\']}"
+  local IFS=$'\n'
+  COMPREPLY=($(command wslcd --complete-path "$cur" 2>/dev/null))
+  [ ${#COMPREPLY[@]} -eq 0 ] && return
+  # Bash only replaces the text after the last ':', so drop everything up to it.
+  local prefix="${cur%"${cur##*:}"}"
+  COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+  compopt -o nospace 2>/dev/null
+}
+complete -o dirnames -F _wslcd_complete wslcd
+`,
+	"zsh": `
+_wslcd() {
+  local -a matches
+  matches=("${(@f)$(command wslcd --complete-path "$PREFIX" 2>/dev/null)}")
+  matches=(${matches:#})
+  if (( ${#matches} )); then
+    compadd -U -Q -S '' -- "${matches[@]}"
+  else
+    _path_files -/
+  fi
+}
+compdef _wslcd wslcd
+`,
+	"fish": `
+function __wslcd_complete
+    set -l tok (commandline -ct)
+    set -l words (command wslcd --complete-path $tok 2>/dev/null)
+    if test (count $words) -gt 0
+        printf '%s\n' $words
+    else
+        __fish_complete_directories $tok
+    end
+end
+complete -c wslcd -f -a '(__wslcd_complete)'
+`,
+}
+
+// loadHints tell how to load each shell's script, by whether it includes completion.
+var loadHints = map[string][2]string{
+	"bash":       {`eval "$(command wslcd --wrapper --shell bash)"`, `eval "$(command wslcd --completion bash)"`},
+	"zsh":        {`eval "$(command wslcd --wrapper --shell zsh)"`, `eval "$(command wslcd --completion zsh)"`},
+	"fish":       {`command wslcd --wrapper --shell fish | source`, `command wslcd --completion fish | source`},
+	"powershell": {`wsl.exe -e wslcd --wrapper --shell powershell | Out-String | Invoke-Expression`},
+}
+
+// wrapperScript returns the wrapper function for shell.
+func wrapperScript(shell string) (string, error) {
+	script, ok := wrapperScripts[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell for the wrapper: %s (want bash, zsh, fish or powershell)", shell)
+	}
+	return fmt.Sprintf("# wslcd wrapper for %s. Load with:\n#   %s\n", shell, loadHints[shell][0]) + script, nil
+}
+
+// completionScript returns the wrapper and completion script for shell.
+func completionScript(shell string) (string, error) {
+	completion, ok := completionScripts[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell for completion: %s (want bash, zsh or fish)", shell)
+	}
+	header := fmt.Sprintf("# wslcd wrapper and completion for %s. Load with:\n#   %s\n", shell, loadHints[shell][1])
+	if shell == "zsh" {
+		header = "# wslcd wrapper and completion for zsh (after compinit). Load with:\n#   " + loadHints[shell][1] + "\n"
+	}
+	return header + wrapperScripts[shell] + completion, nil
+}
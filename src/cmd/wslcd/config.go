@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"wslcd/pkg/wslpath"
+)
+
+// wslConfPath is the WSL per-distro configuration file.
+const wslConfPath = "/etc/wsl.conf"
+
+// settings are the defaults read from the config file at startup. Environment variables override them,
+// and command-line flags override both.
+type settings struct {
+	mountRoots    []string // mount_roots: colon-separated, like WSLCD_MNT_ROOTS
+	tieBreak      string   // tiebreak: like WSLCD_TIEBREAK
+	caseSensitive bool     // case_sensitive: like --case-sensitive
+	fuzzy         bool     // fuzzy: like --fuzzy
+}
+
+// conf is set once from the config file.
+var conf settings
+
+// configDir returns the directory of wslcd's configuration: $XDG_CONFIG_HOME/wslcd, or ~/.config/wslcd.
+func configDir(home string) (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", home, ".config")
+}
+
+// stateDir returns the directory of wslcd's state: $XDG_STATE_HOME/wslcd, or ~/.local/state/wslcd.
+func stateDir(home string) (string, error) {
+	return xdgDir("XDG_STATE_HOME", home, ".local", "state")
+}
+
+// xdgDir returns the wslcd directory under the base directory named by the XDG variable env, or under
+// the default below home. The XDG spec says a relative path in the variable is to be ignored.
+func xdgDir(env, home string, def ...string) (string, error) {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, "wslcd"), nil
+	}
+	if home == "" {
+		return "", fmt.Errorf("HOME is not set (nor %s)", env)
+	}
+	return filepath.Join(append(append([]string{home}, def...), "wslcd")...), nil
+}
+
+// configFile returns the location of the config file, config in configDir.
+func configFile(home string) (string, error) {
+	dir, err := configDir(home)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config"), nil
+}
+
+// loadConfig reads the config file: "key = value" lines, with blank lines and lines starting with '#'
+// skipped. A missing file sets nothing. Unknown keys and invalid values are warned about and skipped,
+// so a config written for a newer version still loads.
+func loadConfig(home string) settings {
+	var s settings
+	path, err := configFile(home)
+	if err != nil {
+		return s
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "warning: cannot read config: %v\n", err)
+		}
+		return s
+	}
+	defer f.Close()
+
+	warn := func(n int, format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "warning: %s:%d: %s\n", path, n, fmt.Sprintf(format, a...))
+	}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			warn(n, "expected key = value")
+			continue
+		}
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		switch k {
+		case "mount_roots":
+			s.mountRoots = nil
+			for _, r := range strings.Split(v, ":") {
+				if r != "" {
+					s.mountRoots = append(s.mountRoots, filepath.Clean(r))
+				}
+			}
+		case "tiebreak":
+			switch v {
+			case wslpath.TieBreakLexical, wslpath.TieBreakShallow, wslpath.TieBreakDeep:
+				s.tieBreak = v
+			default:
+				warn(n, "invalid tiebreak %q (want lexical, shallow or deep)", v)
+			}
+		case "case_sensitive", "fuzzy":
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				warn(n, "invalid %s %q (want true or false)", k, v)
+				continue
+			}
+			if k == "fuzzy" {
+				s.fuzzy = b
+			} else {
+				s.caseSensitive = b
+			}
+		default:
+			warn(n, "unknown key %q", k)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot read config: %v\n", err)
+	}
+	return s
+}
+
+// mountRoots returns the directories Windows drives may be mounted under, in the order they are tried,
+// and where they came from: the colon-separated WSLCD_MNT_ROOTS if set, else WSLCD_MNT_ROOT, else
+// mount_roots from the config file, else automount.root from /etc/wsl.conf, else /mnt.
+func mountRoots() (roots []string, source string) {
+	for _, r := range strings.Split(os.Getenv("WSLCD_MNT_ROOTS"), ":") {
+		if r != "" {
+			roots = append(roots, filepath.Clean(r))
+		}
+	}
+	if len(roots) > 0 {
+		return roots, "WSLCD_MNT_ROOTS"
+	}
+	if r := os.Getenv("WSLCD_MNT_ROOT"); r != "" {
+		return []string{filepath.Clean(r)}, "WSLCD_MNT_ROOT"
+	}
+	if len(conf.mountRoots) > 0 {
+		path, _ := configFile(os.Getenv("HOME"))
+		return conf.mountRoots, path + " mount_roots"
+	}
+	if r, ok := readINI(wslConfPath, "automount", "root"); ok && r != "" {
+		return []string{filepath.Clean(r)}, wslConfPath + " [automount] root"
+	}
+	return []string{wslpath.DefaultMountRoot}, "default"
+}
+
+// driveMap parses WSLCD_DRIVE_MAP, e.g. "z=/srv/shared,y=/data", into drive mappings by lowercase letter.
+// Invalid entries are traced and skipped.
+func driveMap() map[byte]string {
+	m := map[byte]string{}
+	for _, e := range strings.Split(os.Getenv("WSLCD_DRIVE_MAP"), ",") {
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+		d, dir, _ := strings.Cut(e, "=")
+		d = strings.TrimSuffix(strings.TrimSpace(d), ":")
+		dir = strings.TrimSpace(dir)
+		if len(d) != 1 || !isLetter(d[0]) || !filepath.IsAbs(dir) {
+			tracef(1, "ignoring %q in WSLCD_DRIVE_MAP: want <letter>=<absolute directory>", e)
+			continue
+		}
+		m[strings.ToLower(d)[0]] = filepath.Clean(dir)
+	}
+	return m
+}
+
+// currentDistro returns the name of the running WSL distro, or "" if unknown.
+func currentDistro() string {
+	return os.Getenv("WSL_DISTRO_NAME")
+}
+
+// libOptions translates the command-line flags, environment and ignore file into resolver options.
+func libOptions(home string) wslpath.Options {
+	roots, _ := mountRoots()
+	o := wslpath.Options{
+		MountRoot:        roots[0],
+		MountRoots:       roots[1:],
+		UNCRoot:          os.Getenv("WSLCD_UNC_ROOT"),
+		Distro:           currentDistro(),
+		Parent:           opts.parent,
+		Fuzzy:            opts.fuzzy,
+		CaseSensitive:    opts.caseSensitive,
+		NoFollowSymlinks: opts.noFollow,
+		Physical:         opts.physical,
+		Nearest:          opts.nearest || opts.create,
+		FirstMatch:       opts.firstMatch,
+		BreadthFirst:     opts.breadthFirst,
+		StrictPerms:      opts.strictPerms,
+		LooseDrive:       opts.looseDrive,
+		ExpandShortNames: opts.shortNames,
+		ReparseAsDir:     opts.reparseAsDir,
+		ThroughArchives:  opts.archives,
+		TrimTrailing:     opts.trimTrailing,
+		JoinLines:        opts.joinLines,
+		Suggest:          opts.suggest,
+		Tracef:           tracef,
+		Warn:             os.Stderr,
+	}
+	if opts.traceScore {
+		o.Explain = traceScoreMax
+	}
+	if opts.useWslpath {
+		if p, err := exec.LookPath("wslpath"); err == nil {
+			o.Wslpath = p
+		} else {
+			tracef(1, "wslpath not found; using the internal drive mapping")
+		}
+	}
+	if pats, err := loadIgnore(home); err != nil {
+		tracef(1, "cannot read ignore file: %v", err)
+	} else {
+		o.Ignore = pats
+	}
+	o.DriveMap = driveMap()
+	for _, d := range strings.Split(os.Getenv("WSLCD_EXCLUDE_DRIVES"), ",") {
+		d = strings.TrimSuffix(strings.TrimSpace(d), ":")
+		if len(d) == 1 && isLetter(d[0]) {
+			o.ExcludeDrives += d
+		} else if d != "" {
+			tracef(1, "ignoring %q in WSLCD_EXCLUDE_DRIVES: not a drive letter", d)
+		}
+	}
+	for _, dir := range strings.Split(os.Getenv("WSLCD_PATH"), ":") {
+		if dir != "" {
+			o.SearchPath = append(o.SearchPath, dir)
+		}
+	}
+	if v := os.Getenv("WSLCD_MAX_CANDIDATES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			o.MaxCandidates = n
+		} else {
+			tracef(1, "ignoring invalid WSLCD_MAX_CANDIDATES=%q", v)
+		}
+	}
+	if v := os.Getenv("WSLCD_MAX_DEPTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			o.MaxDepth = n
+		} else {
+			tracef(1, "ignoring invalid WSLCD_MAX_DEPTH=%q", v)
+		}
+	}
+	if v := os.Getenv("WSLCD_PARALLEL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			o.Parallel = n
+		} else {
+			tracef(1, "ignoring invalid WSLCD_PARALLEL=%q", v)
+		}
+	}
+	o.TieBreak = conf.tieBreak
+	switch v := os.Getenv("WSLCD_TIEBREAK"); v {
+	case "":
+	case wslpath.TieBreakLexical, wslpath.TieBreakShallow, wslpath.TieBreakDeep:
+		o.TieBreak = v
+	default:
+		tracef(1, "ignoring invalid WSLCD_TIEBREAK=%q (want lexical, shallow or deep)", v)
+	}
+	if opts.candidates || opts.count || opts.maxResults > 0 {
+		// Keep every match in the result instead of settling ties or ambiguous globs; --max-results
+		// cuts the list down afterwards.
+		o.Choose = func(paths []string) (string, error) { return paths[0], nil }
+	} else if opts.interactive && isTerminal(os.Stdin) {
+		o.Choose = choose
+	}
+	return o
+}
+
+// readINI returns the value of key in [section] of a simple INI file such as wsl.conf.
+// Section and key names are case-insensitive; surrounding quotes on the value are removed.
+func readINI(path, section, key string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	in := false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			in = strings.EqualFold(strings.TrimSpace(line[1:len(line)-1]), section)
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !in || !ok || !strings.EqualFold(strings.TrimSpace(k), key) {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		return v, true
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func TestMaxDepthEnv(t *testing.T) {
+	for v, want := range map[string]int{"": 0, "400": 400, "0": 0, "-3": 0, "deep": 0} {
+		t.Setenv("WSLCD_MAX_DEPTH", v)
+		if got := libOptions(t.TempDir()).MaxDepth; got != want {
+			t.Errorf("WSLCD_MAX_DEPTH=%q: MaxDepth = %d; want %d", v, got, want)
+		}
+	}
+}
+
+func TestXDGDirs(t *testing.T) {
+	files := map[string]func(home string) (string, error){
+		"config":    configFile,
+		"bookmarks": bookmarksFile,
+		"ignore":    ignoreFile,
+		"history":   historyFile,
+	}
+	tests := []struct {
+		config, state string
+		want          map[string]string
+	}{
+		{"", "", map[string]string{
+			"config":    "/home/u/.config/wslcd/config",
+			"bookmarks": "/home/u/.config/wslcd/bookmarks",
+			"ignore":    "/home/u/.config/wslcd/ignore",
+			"history":   "/home/u/.local/state/wslcd/history",
+		}},
+		{"/xdg/config", "/xdg/state", map[string]string{
+			"config":    "/xdg/config/wslcd/config",
+			"bookmarks": "/xdg/config/wslcd/bookmarks",
+			"ignore":    "/xdg/config/wslcd/ignore",
+			"history":   "/xdg/state/wslcd/history",
+		}},
+		// The spec says to ignore a relative path.
+		{"rel/config", "rel/state", map[string]string{
+			"config":  "/home/u/.config/wslcd/config",
+			"history": "/home/u/.local/state/wslcd/history",
+		}},
+	}
+	for _, tt := range tests {
+		t.Setenv("XDG_CONFIG_HOME", tt.config)
+		t.Setenv("XDG_STATE_HOME", tt.state)
+		for name, want := range tt.want {
+			if got, err := files[name]("/home/u"); err != nil || got != want {
+				t.Errorf("%s file with XDG_CONFIG_HOME=%q XDG_STATE_HOME=%q = %q, %v; want %q", name, tt.config, tt.state, got, err, want)
+			}
+		}
+	}
+
+	// Without HOME only the XDG variables can say where the files go.
+	t.Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	t.Setenv("XDG_STATE_HOME", "")
+	if got, err := configDir(""); err != nil || got != "/xdg/config/wslcd" {
+		t.Errorf("configDir without HOME = %q, %v; want /xdg/config/wslcd", got, err)
+	}
+	if got, err := stateDir(""); err == nil {
+		t.Errorf("stateDir without HOME or XDG_STATE_HOME = %q; want an error", got)
+	}
+}
+
+func TestExcludeDrivesEnv(t *testing.T) {
+	for v, want := range map[string]string{"": "", "d": "d", "D:, e ,x": "Dex", "d,mnt,1": "d"} {
+		t.Setenv("WSLCD_EXCLUDE_DRIVES", v)
+		if got := libOptions(t.TempDir()).ExcludeDrives; got != want {
+			t.Errorf("WSLCD_EXCLUDE_DRIVES=%q: ExcludeDrives = %q; want %q", v, got, want)
+		}
+	}
+}
+
+func TestDriveMapEnv(t *testing.T) {
+	t.Setenv("WSLCD_DRIVE_MAP", " Z:=/srv/shared/ ,y=/data,x=relative,ab=/no,, w")
+	got := driveMap()
+	want := map[byte]string{'z': "/srv/shared", 'y': "/data"}
+	if len(got) != len(want) || got['z'] != want['z'] || got['y'] != want['y'] {
+		t.Errorf("driveMap() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"wslcd/pkg/wslpath"
+)
+
+// doctor checks the environment wslcd depends on, prints a checklist to stderr and returns the exit
+// code: exitFailure if a check that breaks Windows path resolution failed, else 0.
+func doctor(home string) int {
+	failed := false
+	check := func(status, format string, a ...any) {
+		if status == "FAIL" {
+			failed = true
+		}
+		fmt.Fprintf(os.Stderr, "[%s]%s %s\n", status, strings.Repeat(" ", 4-len(status)), fmt.Sprintf(format, a...))
+	}
+
+	if _, err := os.Stat(wslConfPath); err != nil {
+		check("ok", "%s: not present, using the defaults", wslConfPath)
+	} else if r, ok := readINI(wslConfPath, "automount", "root"); ok {
+		check("ok", "%s: [automount] root = %s", wslConfPath, r)
+	} else {
+		check("ok", "%s: no [automount] root, using %s", wslConfPath, wslpath.DefaultMountRoot)
+	}
+
+	o := libOptions(home)
+	o.Wslpath = "" // check the internal mapping, which is what is used without --use-wslpath
+	roots, source := mountRoots()
+	var drives []string
+	readable := 0
+	for _, root := range roots {
+		ents, err := os.ReadDir(root)
+		if err != nil {
+			// With fallback roots, one missing root is expected as long as another has the drives.
+			status := "FAIL"
+			if len(roots) > 1 {
+				status = "warn"
+			}
+			check(status, "mount root %s (%s): %v", root, source, err)
+			continue
+		}
+		readable++
+		check("ok", "mount root %s (%s) is readable", root, source)
+		for _, e := range ents {
+			if n := e.Name(); len(n) == 1 && isLetter(n[0]) {
+				// Map the letter the way a path would be, so case clashes and bad entries show up here.
+				if p, err := wslpath.MapDrive(n[0], o); err != nil {
+					first, _, _ := strings.Cut(err.Error(), "\n")
+					check("warn", "drive %s: %s", strings.ToUpper(n), first)
+				} else if info, err := os.Stat(p); err != nil || !info.IsDir() {
+					check("warn", "drive %s: %s is not a directory", strings.ToUpper(n), p)
+				} else {
+					drives = append(drives, strings.ToUpper(n)+": -> "+p)
+				}
+			}
+		}
+	}
+	switch {
+	case readable == 0:
+		check("FAIL", "no drives: no mount root is readable")
+	case len(drives) == 0:
+		check("FAIL", "no drive directories under %s", strings.Join(roots, " or "))
+	default:
+		check("ok", "drives: %s", strings.Join(drives, ", "))
+	}
+
+	if home == "" {
+		check("warn", "HOME is not set: bookmarks and history are unavailable")
+	} else {
+		check("ok", "HOME is %s", home)
+	}
+
+	if p, err := exec.LookPath("wslpath"); err != nil {
+		check("warn", "wslpath not found: --use-wslpath falls back to the internal drive mapping")
+	} else {
+		check("ok", "wslpath is %s", p)
+	}
+
+	if failed {
+		return exitFailure
+	}
+	return 0
+}
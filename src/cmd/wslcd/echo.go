@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// echoInput writes the arguments exactly as wslcd received them, for telling shell quoting problems
+// apart from resolution ones: each is quoted, followed by the offset and hex bytes of every separator,
+// quote, space or non-printable character in it.
+func echoInput(w io.Writer, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(w, "no arguments received")
+	}
+	for i, a := range args {
+		fmt.Fprintf(w, "argument %d: %q (%d bytes)\n", i+1, a, len(a))
+		for off := 0; off < len(a); {
+			r, size := utf8.DecodeRuneInString(a[off:])
+			if note := echoNote(r, size); note != "" {
+				fmt.Fprintf(w, "  offset %3d: % x  %s\n", off, a[off:off+size], note)
+			}
+			off += size
+		}
+	}
+}
+
+// echoNote describes a character worth pointing out in echoInput, or returns "" for an ordinary one.
+func echoNote(r rune, size int) string {
+	switch {
+	case r == utf8.RuneError && size == 1:
+		return "invalid UTF-8"
+	case r == '\\':
+		return `backslash \`
+	case r == '/':
+		return "slash /"
+	case r == ':':
+		return "colon :"
+	case r == '"' || r == '\'':
+		return fmt.Sprintf("quote %c", r)
+	case r == ' ':
+		return "space"
+	case r == '\r':
+		return "carriage return"
+	case unicode.IsSpace(r):
+		return "whitespace"
+	case !unicode.IsPrint(r):
+		return "non-printable"
+	}
+	return ""
+}
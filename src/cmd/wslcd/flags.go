@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flagSpec describes one command-line option. The table of them drives parseArgs, the --help text and
+// the --man page, so an option is documented wherever it is accepted.
+type flagSpec struct {
+	names []string // spellings, short first, e.g. "-w", "--to-windows"
+	arg   string   // name of the value the option takes, e.g. "DIR"; "" for a switch
+	// help describes the option, one string per output line. An option without help is listed on the
+	// line of the one before it, as the opposite of it; a hidden one is not listed at all.
+	help   []string
+	hidden bool
+	set    func(o *options, v string) error
+}
+
+// on returns a setter for a switch that stores true in the field chosen by f.
+func on(f func(o *options) *bool) func(o *options, v string) error {
+	return func(o *options, _ string) error { *f(o) = true; return nil }
+}
+
+// off is on, storing false, for the --no-... form of a switch.
+func off(f func(o *options) *bool) func(o *options, v string) error {
+	return func(o *options, _ string) error { *f(o) = false; return nil }
+}
+
+// str returns a setter storing the option's value in the field chosen by f.
+func str(f func(o *options) *string) func(o *options, v string) error {
+	return func(o *options, v string) error { *f(o) = v; return nil }
+}
+
+// flags lists every option in --help order.
+var flags = []flagSpec{
+	{names: []string{"-w", "--to-windows"}, set: on(func(o *options) *bool { return &o.toWindows }),
+		help: []string{"print the Windows form of a Linux path (default: current directory)"}},
+	{names: []string{"-p", "--parent"}, set: on(func(o *options) *bool { return &o.parent }),
+		help: []string{"if the path is a file, resolve to the directory containing it"}},
+	{names: []string{"-i", "--interactive"}, set: on(func(o *options) *bool { return &o.interactive }),
+		help: []string{"prompt on the tty when several directories match equally well"}},
+	{names: []string{"--fuzzy"}, set: on(func(o *options) *bool { return &o.fuzzy }),
+		help: []string{"fall back to approximate matching when a Windows path segment has no match"}},
+	{names: []string{"--no-fuzzy"}, set: off(func(o *options) *bool { return &o.fuzzy }),
+		help: []string{"do not, even if the config file turns on fuzzy"}},
+	{names: []string{"--case-sensitive"}, set: on(func(o *options) *bool { return &o.caseSensitive }),
+		help: []string{"require exact-case matches for drive letters and Windows path segments"}},
+	{names: []string{"--no-case-sensitive"}, set: off(func(o *options) *bool { return &o.caseSensitive })},
+	{names: []string{"--no-follow-symlinks"}, set: on(func(o *options) *bool { return &o.noFollow }),
+		help: []string{"do not resolve through symlinked directories; only the final component may be a symlink"}},
+	{names: []string{"--expand-short-names"}, set: on(func(o *options) *bool { return &o.shortNames }),
+		help: []string{"expand Windows 8.3 short names like PROGRA~1 to the directory they abbreviate"}},
+	{names: []string{"--suggest"}, set: on(func(o *options) *bool { return &o.suggest }),
+		help: []string{"when a segment matches nothing, suggest the closest directory names"}},
+	{names: []string{"--join-lines"}, set: on(func(o *options) *bool { return &o.joinLines }),
+		help: []string{"join a path that was wrapped over several lines when copied, dropping the newlines"}},
+	{names: []string{"--trim-trailing"}, set: on(func(o *options) *bool { return &o.trimTrailing }),
+		help: []string{`if a segment has no match, ignore trailing spaces and dots, e.g. "Docs" finds "Docs "`}},
+	{names: []string{"--treat-reparse-as-dir"}, set: on(func(o *options) *bool { return &o.reparseAsDir }),
+		help: []string{"take a reparse point that fails with an I/O error, like a OneDrive placeholder, for a directory"}},
+	{names: []string{"--through-archives"}, set: on(func(o *options) *bool { return &o.archives }),
+		help: []string{"descend into an archive like x.zip matched by a segment when a FUSE tool has it mounted",
+			"on itself or on its name without the extension"}},
+	{names: []string{"--use-wslpath"}, set: on(func(o *options) *bool { return &o.useWslpath }),
+		help: []string{"map Windows paths with the wslpath utility when it is installed"}},
+	{names: []string{"-P", "--physical"}, set: on(func(o *options) *bool { return &o.physical }),
+		help: []string{"resolve .. in Linux paths after following symlinks, like cd -P"}},
+	{names: []string{"--stdin"}, set: on(func(o *options) *bool { return &o.stdin }),
+		help: []string{"read <path> from stdin (the default without <path> when stdin is not a tty)"}},
+	{names: []string{"--clip"}, set: on(func(o *options) *bool { return &o.clip }),
+		help: []string{"read <path> from the Windows clipboard (with powershell.exe Get-Clipboard)"}},
+	{names: []string{"--root"}, arg: "DIR", set: str(func(o *options) *string { return &o.root }),
+		help: []string{"resolve relative Linux paths against DIR instead of the current directory"}},
+	{names: []string{"--any"}, set: on(func(o *options) *bool { return &o.anyDrive }),
+		help: []string{"look for <path> (without a drive) on every drive, e.g. --any Projects/MyRepo"}},
+	{names: []string{"--loose-drive"}, set: on(func(o *options) *bool { return &o.looseDrive }),
+		help: []string{"if the drive of a Windows path is not mounted, look for the rest of it on every drive",
+			"and use the one drive that has it, e.g. for a mistyped drive letter"}},
+	{names: []string{"--by-label"}, arg: "LABEL", set: str(func(o *options) *string { return &o.label }),
+		help: []string{"resolve <path> (without a drive) beneath the volume mounted as LABEL, e.g. /mnt/My USB"}},
+	{names: []string{"--timeout"}, arg: "DURATION", set: setTimeout,
+		help: []string{"stop scanning after DURATION (e.g. 2s) and use the best match found so far"}},
+	{names: []string{"--breadth-first"}, set: on(func(o *options) *bool { return &o.breadthFirst }),
+		help: []string{"walk a Windows path level by level, stopping early at an exact-case match, or",
+			"branch by branch (the default); both find the same directories"}},
+	{names: []string{"--depth-first"}, set: off(func(o *options) *bool { return &o.breadthFirst })},
+	{names: []string{"--first-match"}, set: on(func(o *options) *bool { return &o.firstMatch }),
+		help: []string{"take the first directory found rather than searching for the best case match"}},
+	{names: []string{"--nearest"}, set: on(func(o *options) *bool { return &o.nearest }),
+		help: []string{"if the path does not exist, resolve to its deepest existing ancestor"}},
+	{names: []string{"-m", "--create"}, set: on(func(o *options) *bool { return &o.create }),
+		help: []string{"create the directories of the path that do not exist yet, named as typed"}},
+	{names: []string{"--relative-to"}, arg: "BASE", set: str(func(o *options) *string { return &o.relativeTo }),
+		help: []string{"print the directory relative to BASE, e.g. ../other, instead of as an absolute path"}},
+	{names: []string{"--resolve-symlinks"}, set: on(func(o *options) *bool { return &o.canonical }),
+		help: []string{"print the directory with every symlink in it followed, e.g. one from /mnt/c into /home"}},
+	{names: []string{"--absolute"}, set: on(func(o *options) *bool { return &o.absolute }),
+		help: []string{"make sure the printed path is absolute, resolving it against the current directory"}},
+	{names: []string{"--best-effort"}, set: on(func(o *options) *bool { return &o.bestEffort }),
+		help: []string{"if resolution fails, report it on stderr but print the path as typed (mapped and",
+			"cleaned) and exit 0, for shell prompts"}},
+	{names: []string{"--strict-perms"}, set: on(func(o *options) *bool { return &o.strictPerms }),
+		help: []string{"fail if the directory resolves but cannot be read and entered, rather than warn"}},
+	{names: []string{"--check"}, set: on(func(o *options) *bool { return &o.check }),
+		help: []string{"resolve and print the path without recording history or saving bookmarks"}},
+	{names: []string{"--json"}, set: on(func(o *options) *bool { return &o.json }),
+		help: []string{"print the result (or error) as a JSON object on stdout"}},
+	{names: []string{"--candidates", "--list"}, set: on(func(o *options) *bool { return &o.candidates }),
+		help: []string{"print every top-scoring match and its score instead of choosing one"}},
+	{names: []string{"--count"}, set: on(func(o *options) *bool { return &o.count }),
+		help: []string{"print how many directories tie for the best match (0 none, 1 unambiguous) and exit 0"}},
+	{names: []string{"--max-results"}, arg: "N", set: setMaxResults,
+		help: []string{"list at most N candidates with --candidates or --json"}},
+	{names: []string{"--append-slash"}, set: on(func(o *options) *bool { return &o.appendSlash }),
+		help: []string{`end the printed directory with / (or \ with --to-windows)`}},
+	{names: []string{"--with-input"}, set: on(func(o *options) *bool { return &o.withInput }),
+		help: []string{"print the input as typed and a tab before the result, e.g. for audit logs (not with --json)"}},
+	{names: []string{"-0", "--print0"}, set: on(func(o *options) *bool { return &o.print0 }),
+		help: []string{"end each printed path or record with a NUL byte instead of a newline"}},
+	{names: []string{"-q", "--quiet"}, set: on(func(o *options) *bool { return &o.quiet }),
+		help: []string{"report errors on a single line, without hints or candidate lists"}},
+	{names: []string{"-v", "--verbose"}, set: func(o *options, _ string) error { o.verbose++; return nil },
+		help: []string{"trace resolution decisions to stderr (repeat for more detail)"}},
+	{names: []string{"--trace-score"}, set: on(func(o *options) *bool { return &o.traceScore }),
+		help: []string{"explain on stderr how the best candidates scored, segment by segment, and what decided"}},
+	{names: []string{"--bookmark"}, set: on(func(o *options) *bool { return &o.bookmark }),
+		help: []string{"save <path> (default: current directory) as @<name>"}},
+	{names: []string{"--list-bookmarks"}, set: on(func(o *options) *bool { return &o.listBookmarks }),
+		help: []string{"print saved bookmarks"}},
+	{names: []string{"--history"}, set: on(func(o *options) *bool { return &o.history }),
+		help: []string{"print recently resolved directories, newest first"}},
+	{names: []string{"--completion"}, arg: "SHELL", set: str(func(o *options) *string { return &o.completion }),
+		help: []string{"print the wrapper function and tab completion for bash, zsh or fish"}},
+	{names: []string{"--wrapper"}, set: on(func(o *options) *bool { return &o.wrapper }),
+		help: []string{"print just the wrapper function that changes directory"}},
+	{names: []string{"--shell"}, arg: "SHELL", set: str(func(o *options) *string { return &o.shell }),
+		help: []string{"shell to print --wrapper for: bash (default), zsh, fish or powershell"}},
+	{names: []string{"--complete"}, arg: "PREFIX",
+		set: func(o *options, v string) error {
+			o.completePath, o.completing, o.namesOnly = v, true, true
+			return nil
+		},
+		help: []string{"print the names of directories that could continue a partial Windows path"}},
+	// Used by the completion scripts.
+	{names: []string{"--complete-path"}, arg: "PREFIX", hidden: true,
+		set: func(o *options, v string) error {
+			o.completePath, o.completing = v, true
+			return nil
+		}},
+	{names: []string{"--show-mapping"}, arg: "DRIVE", set: str(func(o *options) *string { return &o.showMapping }),
+		help: []string{"explain how a drive letter is mapped to a directory, for bug reports"}},
+	{names: []string{"--normalize-only"}, set: on(func(o *options) *bool { return &o.normalize }),
+		help: []string{"print <path> mapped to /mnt/<drive> and cleaned as typed, without checking that it exists"}},
+	{names: []string{"--print-mode"}, set: on(func(o *options) *bool { return &o.printMode }),
+		help: []string{"print how <path> would be read (linux, windows, rooted, relative, collapsed, unc,",
+			"wsl, ...) without resolving it"}},
+	{names: []string{"--echo-input"}, set: on(func(o *options) *bool { return &o.echoInput }),
+		help: []string{"print the arguments exactly as received, with the bytes of separators and",
+			"special characters, to check what the shell passed on; nothing is resolved"}},
+	{names: []string{"--doctor"}, set: on(func(o *options) *bool { return &o.doctor }),
+		help: []string{"check the mount root, drives, HOME, /etc/wsl.conf and wslpath, for bug reports"}},
+	{names: []string{"--man"}, set: on(func(o *options) *bool { return &o.man }),
+		help: []string{"print this help as a man page (roff), e.g. wslcd --man | man -l -"}},
+	{names: []string{"-h", "--help"}, set: on(func(o *options) *bool { return &o.help }),
+		help: []string{"show this help"}},
+}
+
+func setTimeout(o *options, v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid --timeout: %s (want e.g. 2s or 500ms)", v)
+	}
+	o.timeout = d
+	return nil
+}
+
+func setMaxResults(o *options, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid --max-results: %s (want a positive number)", v)
+	}
+	o.maxResults = n
+	return nil
+}
+
+// lookupFlag returns the option spelled name.
+func lookupFlag(name string) (*flagSpec, bool) {
+	for i := range flags {
+		for _, n := range flags[i].names {
+			if n == name {
+				return &flags[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// parseArgs applies the options in args to opts, which holds the defaults, and returns the remaining
+// arguments. "-N" goes back N entries in the history, and "--" ends the options.
+func parseArgs(args []string, opts options) (options, []string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		a, inline, hasInline := args[i], "", false
+		if strings.HasPrefix(a, "--") {
+			if name, v, ok := strings.Cut(a, "="); ok {
+				a, inline, hasInline = name, v, true
+			}
+		}
+		if a == "--" {
+			return opts, append(rest, args[i+1:]...), nil
+		}
+		if a == "-vv" || a == "-vvv" { // -v repeated
+			opts.verbose += len(a) - 1
+			continue
+		}
+		f, ok := lookupFlag(a)
+		if !ok {
+			if n, err := strconv.Atoi(a); err == nil && n < 0 {
+				opts.back = -n
+				continue
+			}
+			if len(a) > 1 && a[0] == '-' {
+				return opts, nil, fmt.Errorf("unknown option: %s", a)
+			}
+			rest = append(rest, a)
+			continue
+		}
+		v := inline
+		if f.arg != "" && !hasInline {
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("%s requires a value", a)
+			}
+			i++
+			v = args[i]
+		}
+		if err := f.set(&opts, v); err != nil {
+			return opts, nil, err
+		}
+	}
+	return opts, rest, nil
+}
+
+// flagEntry is one listed option: its spellings with those of any options listed on its line, and its help.
+type flagEntry struct {
+	label string
+	help  []string
+}
+
+// flagEntries groups the options in flags for listing, skipping hidden ones.
+func flagEntries() []flagEntry {
+	var entries []flagEntry
+	for _, f := range flags {
+		if f.hidden {
+			continue
+		}
+		label := strings.Join(f.names, ", ")
+		if f.arg != "" {
+			label += " " + f.arg
+		}
+		if len(f.help) == 0 && len(entries) > 0 {
+			entries[len(entries)-1].label += ", " + label
+			continue
+		}
+		entries = append(entries, flagEntry{label: label, help: f.help})
+	}
+	return entries
+}
+
+// Text shared by --help and --man.
+var (
+	usageLines = []string{
+		"wslcd [options] <path>",
+		"wslcd [options] @<bookmark>",
+		"wslcd [options] --stdin      # e.g. wl-paste | wslcd --stdin",
+		"wslcd [options] --clip       # the path on the Windows clipboard",
+		"wslcd --bookmark <name> [path]",
+		"wslcd --list-bookmarks",
+		"wslcd -<N>                  # go back N directories in the history",
+		"wslcd --history",
+		"wslcd --completion bash|zsh|fish",
+		"wslcd --wrapper [--shell bash|zsh|fish|powershell]",
+		"wslcd --show-mapping <drive> # e.g. --show-mapping C:",
+		"wslcd --doctor",
+		"wslcd --echo-input <path>",
+	}
+	commandLines = []string{
+		"wslcd resolve [options] <path>",
+		"wslcd windows [options] [path]       # --to-windows",
+		"wslcd bookmark <name> [path]",
+		"wslcd bookmarks                      # --list-bookmarks",
+		"wslcd history",
+		"wslcd completion bash|zsh|fish",
+		"wslcd wrapper [--shell SHELL]",
+		"wslcd mapping <drive>                # --show-mapping",
+		"wslcd doctor",
+	}
+	exampleLines = []string{
+		"wslcd /var/log",
+		"wslcd ../src",
+		"wslcd ~/projects",
+		`wslcd "C:\\Users\\me\\Documents"`,
+		`wslcd "D:/Work/Repo"`,
+		"wslcd c:JunkProjectsMyRepo   # collapsed Windows path without separators",
+		`wslcd -w /mnt/c/Users/me     # prints C:\Users\me`,
+	}
+	exitLines = []string{
+		"0 success, 1 other error, 2 bad usage, 3 path does not exist, 4 not a directory,",
+		"5 drive mapping failed, 6 collapsed path could not be segmented, 7 several directories match,",
+		"8 path deeper than WSLCD_MAX_DEPTH",
+	}
+	wrapperLines = []string{
+		"This program prints the resolved target directory. Use a shell wrapper to actually cd:",
+		`  wslcd() { local t; t="$(command wslcd "$@")" || return; [ -z "$t" ] && return; cd -- "$t"; }`,
+		"or install the wrapper together with tab completion:",
+		`  eval "$(command wslcd --completion bash)"`,
+		"or print the wrapper for another shell, e.g. PowerShell on Windows:",
+		"  wsl.exe -e wslcd --wrapper --shell powershell | Out-String | Invoke-Expression",
+	}
+)
+
+// writeUsage writes the --help text.
+func writeUsage(w io.Writer) {
+	fmt.Fprint(w, "wslcd - resolve Linux or Windows-style paths for cd\n\nUsage:\n")
+	for _, l := range usageLines {
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+	fmt.Fprint(w, "\nCommands (each the same as the option it is named after; a bare path is \"resolve\"):\n")
+	for _, l := range commandLines {
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+	fmt.Fprint(w, "A directory named like a command is reached as ./name.\n\nOptions:\n")
+	const col = 21 // where descriptions start
+	for _, e := range flagEntries() {
+		label := "  " + e.label
+		if strings.HasPrefix(e.label, "--") {
+			label = "      " + e.label // aligned with the long names of options that have a short one
+		}
+		if len(label) < col-1 {
+			fmt.Fprintf(w, "%-*s%s\n", col, label, e.help[0])
+		} else {
+			fmt.Fprintf(w, "%s\n%*s%s\n", label, col, "", e.help[0])
+		}
+		for _, h := range e.help[1:] {
+			fmt.Fprintf(w, "%*s%s\n", col, "", h)
+		}
+	}
+	fmt.Fprint(w, "\nExamples:\n")
+	for _, l := range exampleLines {
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+	fmt.Fprint(w, "\nExit status:\n")
+	for _, l := range exitLines {
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+	fmt.Fprintln(w)
+	for _, l := range wrapperLines {
+		fmt.Fprintln(w, l)
+	}
+}
+
+// writeMan writes the --help text as a man page in roff.
+func writeMan(w io.Writer) {
+	fmt.Fprint(w, ".TH WSLCD 1\n.SH NAME\nwslcd \\- resolve Linux or Windows-style paths for cd\n.SH SYNOPSIS\n.nf\n")
+	for _, l := range usageLines {
+		fmt.Fprintln(w, roff(l))
+	}
+	fmt.Fprint(w, ".fi\n.SH DESCRIPTION\n")
+	fmt.Fprintln(w, roff(wrapperLines[0]))
+	fmt.Fprint(w, ".PP\n.nf\n")
+	for _, l := range wrapperLines[1:] {
+		fmt.Fprintln(w, roff(l))
+	}
+	fmt.Fprint(w, ".fi\n.SH COMMANDS\nEach is the same as the option it is named after; a bare path is \\fBresolve\\fR.\n"+
+		"A directory named like a command is reached as ./name.\n.PP\n.nf\n")
+	for _, l := range commandLines {
+		fmt.Fprintln(w, roff(l))
+	}
+	fmt.Fprint(w, ".fi\n.SH OPTIONS\n")
+	for _, e := range flagEntries() {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roff(e.label), roff(strings.Join(e.help, " ")))
+	}
+	fmt.Fprint(w, ".SH EXAMPLES\n.nf\n")
+	for _, l := range exampleLines {
+		fmt.Fprintln(w, roff(l))
+	}
+	fmt.Fprint(w, ".fi\n.SH EXIT STATUS\n")
+	fmt.Fprintln(w, roff(strings.Join(exitLines, " ")))
+}
+
+// roff escapes s for a line of roff text: backslashes and hyphens, and a leading control character.
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// historyMax caps the number of directories kept in the history file.
+const historyMax = 500
+
+// historyFile returns the location of the history file, history in stateDir.
+func historyFile(home string) (string, error) {
+	dir, err := stateDir(home)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history"), nil
+}
+
+// readHistory parses history lines from r, oldest first, keeping only the latest occurrence of each directory.
+func readHistory(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if l := sc.Text(); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	seen := make(map[string]bool)
+	var out []string
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !seen[lines[i]] {
+			seen[lines[i]] = true
+			out = append(out, lines[i])
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, sc.Err()
+}
+
+// loadHistory returns the recorded directories, oldest first. A missing file has no history.
+func loadHistory(home string) ([]string, error) {
+	path, err := historyFile(home)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read history: %v", err)
+	}
+	defer f.Close()
+	return readHistory(f)
+}
+
+// recordHistory appends dir to the history file. The file is locked for the duration so concurrent
+// invocations don't interleave; it is rewritten deduplicated and trimmed to historyMax when needed.
+func recordHistory(home, dir string) error {
+	path, err := historyFile(home)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	hist, err := readHistory(f)
+	if err != nil {
+		return err
+	}
+	if len(hist) > 0 && hist[len(hist)-1] == dir {
+		return nil
+	}
+
+	rewrite := len(hist) >= historyMax
+	for _, h := range hist {
+		if h == dir {
+			rewrite = true
+			break
+		}
+	}
+	if !rewrite {
+		_, err = io.WriteString(f, dir+"\n")
+		return err
+	}
+
+	var kept []string
+	for _, h := range hist {
+		if h != dir {
+			kept = append(kept, h)
+		}
+	}
+	kept = append(kept, dir)
+	if len(kept) > historyMax {
+		kept = kept[len(kept)-historyMax:]
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, strings.Join(kept, "\n")+"\n")
+	return err
+}
+
+// historyEntry returns the directory n entries before the most recent one.
+func historyEntry(home string, n int) (string, error) {
+	hist, err := loadHistory(home)
+	if err != nil {
+		return "", err
+	}
+	if n >= len(hist) {
+		return "", fmt.Errorf("history has no entry -%d (%d entries recorded)", n, len(hist))
+	}
+	return hist[len(hist)-1-n], nil
+}
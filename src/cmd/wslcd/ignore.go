@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFile returns the location of the ignore file, ignore in configDir.
+func ignoreFile(home string) (string, error) {
+	dir, err := configDir(home)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ignore"), nil
+}
+
+// loadIgnore reads the directory name patterns of the ignore file, one per line. Blank lines and
+// lines starting with '#' are skipped. A missing file ignores nothing.
+func loadIgnore(home string) ([]string, error) {
+	path, err := ignoreFile(home)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pats []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		pats = append(pats, line)
+	}
+	return pats, sc.Err()
+}
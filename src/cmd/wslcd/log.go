@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"wslcd/pkg/wslpath"
+)
+
+// logResolution appends a line describing one resolution to the file named by WSLCD_LOG, if set:
+// when it ran, the input, the mode and the result or error, and how long it took. The line is written
+// with a single append so concurrent invocations don't interleave. Logging never affects the result;
+// a failure to write is only traced. Like the history, nothing is logged with --check.
+func logResolution(start time.Time, arg string, r wslpath.Resolution, err error) {
+	path := os.Getenv("WSLCD_LOG")
+	if path == "" || opts.check {
+		return
+	}
+	if r.Mode == "" {
+		r.Mode = "-"
+	}
+	line := fmt.Sprintf("%s pid=%d input=%s mode=%s", start.Format(time.RFC3339Nano), os.Getpid(), strconv.Quote(arg), r.Mode)
+	if err != nil {
+		line += " error=" + strconv.Quote(err.Error())
+	} else {
+		line += " resolved=" + strconv.Quote(r.Resolved)
+	}
+	line += fmt.Sprintf(" elapsed=%s\n", time.Since(start).Round(time.Microsecond))
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err == nil {
+		_, err = f.WriteString(line)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		tracef(1, "cannot write WSLCD_LOG: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wslcd/pkg/wslpath"
+)
+
+func TestLogResolution(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "wslcd.log")
+	t.Setenv("WSLCD_LOG", log)
+	logResolution(time.Now(), `C:\Users`, wslpath.Resolution{Mode: wslpath.ModeWindows, Resolved: "/mnt/c/Users"}, nil)
+	logResolution(time.Now(), "nowhere", wslpath.Resolution{}, errors.New("gone"))
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 2 ||
+		!strings.Contains(lines[0], `input="C:\\Users" mode=windows resolved="/mnt/c/Users" elapsed=`) ||
+		!strings.Contains(lines[1], `input="nowhere" mode=- error="gone" elapsed=`) {
+		t.Errorf("WSLCD_LOG holds %q", lines)
+	}
+}
+
+func TestLogResolutionCheck(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "wslcd.log")
+	t.Setenv("WSLCD_LOG", log)
+	opts = options{check: true}
+	t.Cleanup(func() { opts = options{} })
+	logResolution(time.Now(), `C:\Users`, wslpath.Resolution{Mode: wslpath.ModeWindows, Resolved: "/mnt/c/Users"}, nil)
+	if _, err := os.Stat(log); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("--check wrote WSLCD_LOG (stat: %v)", err)
+	}
+}
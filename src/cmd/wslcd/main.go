@@ -1,322 +1,756 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
+	"strconv"
 	"strings"
-	"unicode"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"wslcd/pkg/wslpath"
+)
+
+// options holds the command-line flags.
+type options struct {
+	help        bool
+	toWindows   bool
+	parent      bool
+	interactive bool
+	json        bool
+	candidates  bool
+	count       bool // --count: print how many directories tie for the best match
+	maxResults  int  // --max-results: list at most this many candidates
+	print0      bool
+	appendSlash bool // --append-slash: end printed directories with a separator
+	withInput   bool // --with-input: print the input before the result
+	quiet       bool
+	verbose     int
+	traceScore  bool // --trace-score: explain the ranking of the best candidates on stderr
+	man         bool // --man: print the help as a man page
+
+	bookmark      bool
+	listBookmarks bool
+	history       bool
+	fuzzy         bool
+	check         bool // --check: resolve without writing any state
+	bestEffort    bool // --best-effort: on failure print the normalized input and exit 0
+	noFollow      bool
+	physical      bool
+	useWslpath    bool
+	nearest       bool
+	create        bool // --create: make the directories of the path that do not exist yet
+	firstMatch    bool // --first-match: stop at the first full match instead of weighing them all
+	breadthFirst  bool // --breadth-first: walk Windows paths level by level
+	absolute      bool // --absolute: make sure every printed path is absolute
+	canonical     bool // --resolve-symlinks: print the path with every symlink in it followed
+	shortNames    bool // --expand-short-names: expand 8.3 names like PROGRA~1
+	suggest       bool // --suggest: name the closest directories when a segment matches nothing
+	joinLines     bool // --join-lines: join a path wrapped over several lines
+	trimTrailing  bool // --trim-trailing: let "Docs" match a directory named "Docs " or "Docs."
+	reparseAsDir  bool // --treat-reparse-as-dir: take reparse points stat cannot follow for directories
+	archives      bool // --through-archives: descend into FUSE-mounted archive files
+	strictPerms   bool // --strict-perms: fail when the resolved directory cannot be entered
+	looseDrive    bool // --loose-drive: retry a path on an unmounted drive on every drive
+	anyDrive      bool
+	label         string // --by-label: resolve beneath the volume mounted under this label
+	caseSensitive bool
+
+	root         string // --root: directory relative Linux paths are resolved against instead of the cwd
+	relativeTo   string // --relative-to: print the result relative to this directory
+	completion   string // --completion: shell to emit a completion script for
+	wrapper      bool   // --wrapper: print just the wrapper function for --shell
+	shell        string // --shell: shell to emit the wrapper for
+	completePath string // --complete-path: word to complete (used by the completion scripts)
+	completing   bool
+	showMapping  string        // --show-mapping: drive letter to diagnose
+	doctor       bool          // --doctor: check the environment and print a checklist
+	echoInput    bool          // --echo-input: print the arguments as received, to debug shell quoting
+	printMode    bool          // --print-mode: print the detected input mode without resolving
+	normalize    bool          // --normalize-only: print the input mapped and cleaned, without touching the filesystem
+	namesOnly    bool          // --complete: print just the directory names, for editor plugins
+	stdin        bool          // --stdin: read the path from stdin
+	clip         bool          // --clip: read the path from the Windows clipboard
+	timeout      time.Duration // --timeout: give up scanning the filesystem after this long
+	back         int           // -N: go back N entries in the history
+}
+
+// Exit codes, by failure category.
+const (
+	exitFailure       = 1 // any other error
+	exitUsage         = 2 // bad options or arguments
+	exitNotFound      = 3 // the path does not exist
+	exitNotDir        = 4 // the path is not a directory
+	exitDriveMapping  = 5 // no mount found for the drive letter
+	exitUnsegmentable = 6 // a collapsed Windows path could not be split into directory names
+	exitAmbiguous     = 7 // several directories match and none was chosen
+	exitTooDeep       = 8 // a Windows path walk went past WSLCD_MAX_DEPTH
 )
 
+// opts is set once from the command line.
+var opts options
+
+// cwdErr is set when the current directory is gone, and relative paths cannot be resolved against it.
+var cwdErr error
+
 func main() {
-	if len(os.Args) != 2 || os.Args[1] == "-h" || os.Args[1] == "--help" {
+	var args []string
+	var err error
+	conf = loadConfig(os.Getenv("HOME"))
+	opts, args, err = parseArgs(expandSubcommand(os.Args[1:]), options{fuzzy: conf.fuzzy, caseSensitive: conf.caseSensitive})
+	if err != nil {
+		failf(exitUsage, "error: %v", err)
+	}
+	if opts.help {
 		usage()
 		return
 	}
+	if opts.man {
+		writeMan(os.Stdout)
+		return
+	}
+	if opts.echoInput {
+		echoInput(os.Stderr, args)
+		return
+	}
 
-	arg := os.Args[1]
-	cwd, err := os.Getwd()
-	if err != nil {
-		failf("error: unable to get current working directory: %v", err)
+	// Only relative paths need the cwd, so its being gone is reported when one is resolved.
+	var cwd string
+	cwd, cwdErr = currentDir()
+	if opts.relativeTo != "" {
+		if opts.absolute {
+			failf(exitUsage, "error: --relative-to and --absolute cannot be combined")
+		}
+		if !filepath.IsAbs(opts.relativeTo) {
+			opts.relativeTo = filepath.Join(cwd, opts.relativeTo)
+		}
+	}
+	if opts.root != "" {
+		root := filepath.Join(cwd, opts.root)
+		if filepath.IsAbs(opts.root) {
+			root = filepath.Clean(opts.root)
+		} else if cwdErr != nil {
+			failf(exitFailure, "error: cannot resolve --root %s: %v", opts.root, cwdErr)
+		}
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			failf(exitUsage, "error: --root %s is not a directory", opts.root)
+		}
+		opts.root = root
 	}
 
 	home := os.Getenv("HOME")
 
-	target, err := ResolveTarget(arg, cwd, home)
-	if err != nil {
-		failf("%v", err)
+	if opts.wrapper {
+		shell := opts.shell
+		if shell == "" {
+			shell = "bash"
+		}
+		script, err := wrapperScript(shell)
+		if err != nil {
+			failf(exitUsage, "error: %v", err)
+		}
+		fmt.Print(script)
+		return
 	}
 
-	// Print the resolved path for the shell wrapper to cd into.
-	fmt.Println(target)
-}
+	if opts.completion != "" {
+		script, err := completionScript(opts.completion)
+		if err != nil {
+			failf(exitCode(err), "error: %v", err)
+		}
+		fmt.Print(script)
+		return
+	}
 
-func usage() {
-	fmt.Fprintf(os.Stderr, `wslcd - resolve Linux or Windows-style paths for cd
-
-Usage:
-  wslcd <path>
-
-Examples:
-  wslcd /var/log
-  wslcd ../src
-  wslcd ~/projects
-  wslcd "C:\\Users\\me\\Documents"
-  wslcd "D:/Work/Repo"
-  wslcd c:JunkProjectsMyRepo   # collapsed Windows path without separators
-
-This program prints the resolved target directory. Use a shell wrapper to actually cd:
-  wslcd() { local t; t="$(command wslcd "$@")" || return; [ -z "$t" ] && return; cd -- "$t"; }
-`)
-}
+	if opts.showMapping != "" {
+		os.Exit(showMapping(opts.showMapping, home))
+	}
 
-func failf(format string, a ...any) {
-	fmt.Fprintf(os.Stderr, format+"\n", a...)
-	os.Exit(1)
-}
+	if opts.doctor {
+		os.Exit(doctor(home))
+	}
 
-// ResolveTarget resolves arg either as a Linux path or a Windows path mapped under /mnt/<drive>.
-// Returns an absolute path to an existing directory.
-func ResolveTarget(arg, cwd, home string) (string, error) {
-	arg = strings.TrimSpace(arg)
-	if arg == "" {
-		return "", errors.New("error: missing target directory")
+	if opts.completing {
+		complete := wslpath.Complete
+		if opts.namesOnly {
+			complete = wslpath.CompleteNames
+		}
+		for _, w := range complete(opts.completePath, libOptions(home)) {
+			fmt.Println(w)
+		}
+		return
 	}
 
-	// Standard Windows path (e.g., C:\\ or C:/)
-	if isWindowsPath(arg) {
-		return resolveWindowsPath(arg)
+	if opts.listBookmarks {
+		bms, err := loadBookmarks(home)
+		if err != nil {
+			failf(exitCode(err), "error: %v", err)
+		}
+		for _, b := range bms {
+			fmt.Printf("%s\t%s\n", b.name, b.path)
+		}
+		return
 	}
-	// Collapsed Windows path like "C:FooBarBaz" (shell ate backslashes)
-	if looksLikeWindowsDriveNoSlash(arg) {
-		return resolveWindowsPathCollapsed(arg)
+
+	if opts.history {
+		hist, err := loadHistory(home)
+		if err != nil {
+			failf(exitCode(err), "error: %v", err)
+		}
+		for i := len(hist) - 1; i >= 0; i-- {
+			fmt.Printf("%3d  %s\n", len(hist)-1-i, hist[i])
+		}
+		return
 	}
 
-	// Linux path semantics
-	p, err := resolveLinuxLike(arg, cwd, home)
-	if err != nil {
-		return "", err
+	if opts.back > 0 {
+		if len(args) != 0 {
+			usage()
+			os.Exit(exitUsage)
+		}
+		p, err := historyEntry(home, opts.back)
+		if err != nil {
+			failf(exitCode(err), "error: %v", err)
+		}
+		args = []string{p}
 	}
-	// verify dir
-	info, err := os.Stat(p)
-	if err != nil {
-		return "", fmt.Errorf("error: %s", err)
+
+	if opts.bookmark {
+		if len(args) == 0 || len(args) > 2 {
+			usage()
+			os.Exit(exitUsage)
+		}
+		p := cwd
+		if len(args) == 2 {
+			var r wslpath.Resolution
+			if r, err = resolve(args[1], cwd, home); err != nil {
+				failf(exitCode(err), "error: %v", err)
+			}
+			p = r.Resolved
+		}
+		if opts.check {
+			return
+		}
+		if err := saveBookmark(home, args[0], p); err != nil {
+			failf(exitCode(err), "error: %v", err)
+		}
+		return
 	}
-	if !info.IsDir() {
-		return "", fmt.Errorf("error: not a directory: %s", p)
+
+	if opts.toWindows {
+		if len(args) > 1 {
+			usage()
+			os.Exit(exitUsage)
+		}
+		p := cwd
+		if len(args) == 1 {
+			if p, err = wslpath.ResolveLinuxLike(strings.TrimSpace(args[0]), rootFor(args[0], cwd, home), home, libOptions(home)); err != nil {
+				failf(exitCode(err), "error: %v", err)
+			}
+		}
+		// A WSLCD_DRIVE_MAP directory or the first mount root that holds p gives it a drive letter;
+		// otherwise it maps to \\wsl$.
+		roots, _ := mountRoots()
+		win, mapped := mappedWindowsPath(p)
+		for _, root := range roots {
+			if mapped {
+				break
+			}
+			if win, err = wslpath.ToWindowsPath(p, currentDistro(), root); err == nil && !strings.HasPrefix(win, `\\`) {
+				break
+			}
+		}
+		if err != nil {
+			failf(exitCode(err), "error: %v", err)
+		}
+		input := cwd
+		if len(args) == 1 {
+			input = args[0]
+		}
+		printResult(input, withSlash(win, `\`))
+		return
 	}
-	return p, nil
-}
 
-// resolveLinuxLike resolves ~, relative, and cleans the path.
-func resolveLinuxLike(arg, cwd, home string) (string, error) {
-	p := arg
-	// ~ or ~/...
-	if p == "~" {
-		if home == "" {
-			return "", errors.New("error: HOME is not set")
+	if opts.label != "" && len(args) == 0 && !opts.stdin {
+		args = []string{""} // the volume itself
+	}
+
+	if opts.clip {
+		if len(args) != 0 || opts.stdin {
+			usage()
+			os.Exit(exitUsage)
 		}
-		p = home
-	} else if strings.HasPrefix(p, "~/") {
-		if home == "" {
-			return "", errors.New("error: HOME is not set")
+		p, err := readClipboard()
+		if err != nil {
+			failf(exitFailure, "error: %v", err)
 		}
-		p = filepath.Join(home, p[2:])
-	} else if !strings.HasPrefix(p, "/") {
-		// relative
-		p = filepath.Join(cwd, p)
+		args = []string{p}
 	}
-	return filepath.Clean(p), nil
-}
 
-// isWindowsPath detects drive-letter rooted paths like "C:\\..." or "d:/...".
-func isWindowsPath(p string) bool {
-	if len(p) < 3 {
-		return false
+	if opts.stdin || (len(args) == 0 && !isTerminal(os.Stdin)) {
+		if len(args) != 0 {
+			usage()
+			os.Exit(exitUsage)
+		}
+		p, err := readTarget(os.Stdin)
+		if err != nil {
+			failf(exitFailure, "error: %v", err)
+		}
+		args = []string{p}
 	}
-	// [A-Za-z]:[/\]
-	r0 := rune(p[0])
-	if !unicode.IsLetter(r0) {
-		return false
+
+	if len(args) != 1 {
+		usage()
+		os.Exit(exitUsage)
 	}
-	if p[1] != ':' {
-		return false
+
+	if opts.normalize {
+		printResult(args[0], withSlash(normalize(args[0], cwd, home), "/"))
+		return
+	}
+
+	if opts.printMode {
+		mode, err := detectMode(args[0], home)
+		if err != nil {
+			failf(exitCode(err), "error: %v", err)
+		}
+		printResult(args[0], mode)
+		return
+	}
+
+	if opts.candidates {
+		r, err := resolve(args[0], cwd, home)
+		if err != nil {
+			failf(exitCode(err), "error: %v", err)
+		}
+		limitCandidates(&r)
+		relativize(&r)
+		for _, c := range r.Candidates {
+			printResult(args[0], withSlash(c, "/"), strconv.Itoa(r.Score))
+		}
+		return
+	}
+
+	if opts.count {
+		r, err := resolve(args[0], cwd, home)
+		if code := exitCode(err); err != nil && code != exitNotFound && code != exitUnsegmentable {
+			if opts.json {
+				printJSON(map[string]string{"error": errorText(err.Error())})
+				os.Exit(code)
+			}
+			failf(code, "error: %v", err)
+		}
+		n := len(r.Candidates)
+		if err != nil {
+			tracef(1, "no match: %v", err)
+			n = 0
+		}
+		if opts.json {
+			relativize(&r)
+			printJSON(jsonResult{Resolution: r, Count: &n})
+		} else {
+			printResult(args[0], strconv.Itoa(n))
+		}
+		return
 	}
-	sep := p[2]
-	return sep == '\\' || sep == '/'
+
+	if opts.json {
+		r, err := resolve(args[0], cwd, home)
+		if err != nil {
+			printJSON(map[string]string{"error": errorText(err.Error())})
+			os.Exit(exitCode(err))
+		}
+		remember(home, r.Resolved)
+		truncated := limitCandidates(&r)
+		relativize(&r)
+		r.Resolved = withSlash(r.Resolved, "/")
+		printJSON(jsonResult{Resolution: r, Truncated: truncated})
+		return
+	}
+
+	r, err := resolve(args[0], cwd, home)
+	if err != nil && opts.bestEffort {
+		// Report the failure but still give prompts something sensible to show.
+		fmt.Fprintf(os.Stderr, "error: %s\n", errorText(err.Error()))
+		printResult(args[0], normalize(args[0], cwd, home))
+		return
+	}
+	if err != nil {
+		failf(exitCode(err), "error: %v", err)
+	}
+	if r.Unmatched > 0 {
+		fmt.Fprintf(os.Stderr, "wslcd: %d trailing segment(s) not found; stopped at %s\n", r.Unmatched, r.Resolved)
+	}
+	remember(home, r.Resolved)
+	relativize(&r)
+
+	// Print the resolved path for the shell wrapper to cd into.
+	printResult(args[0], withSlash(r.Resolved, "/"))
 }
 
-// looksLikeWindowsDriveNoSlash detects inputs like "C:Something" where the path separators were lost.
-func looksLikeWindowsDriveNoSlash(p string) bool {
-	if len(p) < 3 {
-		return false
+// readTarget reads the target path from r, as pasted from a clipboard: surrounding whitespace and a
+// trailing CR are removed. Surrounding quotes are left to the resolver.
+func readTarget(r io.Reader) (string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("cannot read stdin: %v", err)
 	}
-	if !unicode.IsLetter(rune(p[0])) || p[1] != ':' {
-		return false
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return "", errors.New("no path on stdin")
 	}
-	return p[2] != '\\' && p[2] != '/'
+	return s, nil
 }
 
-// resolveWindowsPath maps e.g. "C:\\Foo\\Bar" -> best matching "/mnt/c/Foo/Bar" using case-insensitive segment matching.
-func resolveWindowsPath(win string) (string, error) {
-	drive := unicode.ToLower(rune(win[0]))
-	rest := win[2:] // starts with '\\' or '/'
-	rest = strings.ReplaceAll(rest, "\\", "/")
+// remember records a resolved directory in the history. Failures never affect the result.
+func remember(home, dir string) {
+	if opts.check {
+		return
+	}
+	if err := recordHistory(home, dir); err != nil {
+		tracef(1, "cannot record history: %v", err)
+	}
+}
+
+// jsonResult is the --json record: the resolution, and whether --max-results cut its candidates short.
+type jsonResult struct {
+	wslpath.Resolution
+	Truncated bool `json:"truncated,omitempty"`
+	Count     *int `json:"count,omitempty"` // with --count, even when 0
+}
 
-	// Normalize segments and handle . and ..
-	var segs []string
-	for _, s := range strings.Split(rest, "/") {
-		if s == "" { continue }
-		if s == "." { continue }
-		if s == ".." { if len(segs) > 0 { segs = segs[:len(segs)-1] }; continue }
-		segs = append(segs, s)
+// limitCandidates keeps the first --max-results candidates of r, which come best first, and reports
+// whether any were dropped. The note on stderr says how many there were.
+func limitCandidates(r *wslpath.Resolution) bool {
+	if opts.maxResults <= 0 || len(r.Candidates) <= opts.maxResults {
+		return false
 	}
+	fmt.Fprintf(os.Stderr, "wslcd: showing %d of %d candidates\n", opts.maxResults, len(r.Candidates))
+	r.Candidates = r.Candidates[:opts.maxResults]
+	return true
+}
 
-	mntRoot, err := pickCaseInsensitiveEntry("/mnt", string(drive))
+// printJSON writes v to stdout as a single record of JSON.
+func printJSON(v any) {
+	b, err := json.Marshal(v)
 	if err != nil {
-		return "", fmt.Errorf("error: cannot locate /mnt/%c (drive mapping): %v", drive, err)
+		failf(exitFailure, "error: %v", err)
 	}
-	root := filepath.Join("/mnt", mntRoot)
+	printRecord(string(b))
+}
 
-	cands, err := exploreCandidates(root, segs)
-	if err != nil { return "", err }
-	if len(cands) == 0 {
-		if len(segs) == 0 {
-			info, err := os.Stat(root)
-			if err != nil { return "", fmt.Errorf("error: %v", err) }
-			if !info.IsDir() { return "", fmt.Errorf("error: not a directory: %s", root) }
-			return root, nil
+// relativize rewrites the paths of r relative to the --relative-to directory, once history has the
+// absolute ones. It exits if a path cannot be expressed relative to it.
+func relativize(r *wslpath.Resolution) {
+	if opts.relativeTo == "" {
+		return
+	}
+	rel := func(p string) string {
+		s, err := filepath.Rel(opts.relativeTo, p)
+		if err != nil {
+			failf(exitFailure, "error: cannot express %s relative to %s: %v", p, opts.relativeTo, err)
 		}
-		return "", fmt.Errorf("error: path does not exist (no case-insensitive match): %s", win)
+		return s
 	}
+	r.Resolved = rel(r.Resolved)
+	for i, c := range r.Candidates {
+		r.Candidates[i] = rel(c)
+	}
+}
 
-	sort.SliceStable(cands, func(i, j int) bool {
-		if cands[i].score != cands[j].score { return cands[i].score > cands[j].score }
-		return cands[i].fullPath < cands[j].fullPath
-	})
-	return cands[0].fullPath, nil
+// withSlash ends the directory path p with sep for --append-slash, unless it already does (as "/" and "C:\" do).
+func withSlash(p, sep string) string {
+	if !opts.appendSlash || strings.HasSuffix(p, sep) {
+		return p
+	}
+	return p + sep
 }
 
-// resolveWindowsPathCollapsed greedily matches directory names as case-insensitive prefixes of the tail.
-func resolveWindowsPathCollapsed(win string) (string, error) {
-	drive := unicode.ToLower(rune(win[0]))
-	tail := win[2:]
+// printRecord writes s to stdout terminated by a newline, or by a NUL byte with --print0.
+func printRecord(s string) {
+	end := "\n"
+	if opts.print0 {
+		end = "\x00"
+	}
+	fmt.Print(s + end)
+}
 
-	mntRoot, err := pickCaseInsensitiveEntry("/mnt", string(drive))
-	if err != nil {
-		return "", fmt.Errorf("error: cannot locate /mnt/%c (drive mapping): %v", drive, err)
+// printResult prints fields as one record, separated by tabs or with --print0 by NUL bytes, after the
+// input they were derived from with --with-input.
+func printResult(input string, fields ...string) {
+	if opts.withInput {
+		fields = append([]string{input}, fields...)
+	}
+	sep := "\t"
+	if opts.print0 {
+		sep = "\x00"
+	}
+	printRecord(strings.Join(fields, sep))
+}
+
+func usage() {
+	writeUsage(os.Stderr)
+}
+
+// tracef writes a --verbose trace line to stderr when the verbosity is at least level.
+func tracef(level int, format string, a ...any) {
+	if opts.verbose >= level {
+		fmt.Fprintf(os.Stderr, "wslcd: "+format+"\n", a...)
 	}
-	curr := filepath.Join("/mnt", mntRoot)
+}
 
-	tail = strings.TrimLeft(tail, "\\/")
-	for {
-		if len(tail) == 0 {
-			info, err := os.Stat(curr)
-			if err != nil { return "", fmt.Errorf("error: %v", err) }
-			if !info.IsDir() { return "", fmt.Errorf("error: not a directory: %s", curr) }
-			return curr, nil
-		}
+// failf reports an error on stderr and exits with code.
+func failf(code int, format string, a ...any) {
+	fmt.Fprintln(os.Stderr, errorText(fmt.Sprintf(format, a...)))
+	os.Exit(code)
+}
+
+// exitCode maps a resolution error to its exit code.
+func exitCode(err error) int {
+	switch {
+	// A timed-out walk can surface through any of the checks below; report it as a plain failure.
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitFailure
+	case errors.Is(err, wslpath.ErrNoTarget):
+		return exitUsage
+	case errors.Is(err, wslpath.ErrTooDeep):
+		return exitTooDeep
+	case errors.Is(err, wslpath.ErrDriveMapping):
+		return exitDriveMapping
+	case errors.Is(err, wslpath.ErrUnsegmentable):
+		return exitUnsegmentable
+	case errors.Is(err, wslpath.ErrAmbiguous):
+		return exitAmbiguous
+	case errors.Is(err, wslpath.ErrNotDirectory):
+		return exitNotDir
+	case errors.Is(err, wslpath.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		return exitNotFound
+	}
+	return exitFailure
+}
 
-		if tail[0] == '/' || tail[0] == '\\' { tail = strings.TrimLeft(tail, "\\/"); continue }
+// errorText returns msg as reported, cut to its first line with --quiet so hints and candidate lists are dropped.
+func errorText(msg string) string {
+	if !opts.quiet {
+		return msg
+	}
+	first, _, _ := strings.Cut(msg, "\n")
+	return strings.TrimSuffix(first, ":")
+}
 
-		ents, err := os.ReadDir(curr)
-		if err != nil { return "", fmt.Errorf("error: cannot read directory %s: %v", curr, err) }
+// currentDir returns the current directory and, if it no longer exists, an error saying so. Usually it
+// was deleted from under the shell, e.g. by a branch switch; its path is then taken from PWD.
+func currentDir() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return os.Getenv("PWD"), fmt.Errorf("the current directory %s no longer exists (%v)", os.Getenv("PWD"), err)
+	}
+	if _, err := os.Stat(cwd); err != nil {
+		return cwd, fmt.Errorf("the current directory %s no longer exists (%v)", cwd, err)
+	}
+	return cwd, nil
+}
 
-		type cand struct { name string; plen int; score int }
-		var ms []cand
-		for _, e := range ents {
-			n := e.Name()
-			ln := len(n)
-			if ln > len(tail) { continue }
-			if !strings.EqualFold(tail[:ln], n) { continue }
-			full := filepath.Join(curr, n)
-			isDir, err := isDirFollowSymlink(full, e)
-			if err != nil || !isDir { continue }
-			ms = append(ms, cand{name: n, plen: ln, score: caseScore(tail[:ln], n)})
+// resolve resolves arg, making the result absolute with --absolute.
+func resolve(arg, cwd, home string) (wslpath.Resolution, error) {
+	start := time.Now()
+	if root := rootFor(arg, cwd, home); root != cwd {
+		cwd = root
+	} else if cwdErr != nil && needsCwd(arg, home) {
+		if home == "" {
+			err := fmt.Errorf("%v, and HOME is not set to resolve %s against instead", cwdErr, arg)
+			logResolution(start, arg, wslpath.Resolution{}, err)
+			return wslpath.Resolution{Input: arg}, err
+		}
+		fmt.Fprintf(os.Stderr, "warning: %v; resolving %s against HOME (%s)\n", cwdErr, arg, home)
+		cwd = home
+	}
+	r, err := resolveArg(arg, cwd, home)
+	if err == nil && opts.create && len(r.Missing) > 0 {
+		r, err = create(r)
+	}
+	if err == nil && opts.absolute {
+		r.Resolved = absolute(r.Resolved, cwd)
+		for i, c := range r.Candidates {
+			r.Candidates[i] = absolute(c, cwd)
 		}
+	}
+	if err == nil && opts.canonical {
+		if r.Resolved, err = canonical(r.Resolved); err == nil {
+			for i, c := range r.Candidates {
+				if r.Candidates[i], err = canonical(c); err != nil {
+					break
+				}
+			}
+		}
+	}
+	logResolution(start, arg, r, err)
+	if opts.traceScore {
+		traceScore(os.Stderr, r)
+	}
+	return r, err
+}
 
-		if len(ms) == 0 {
-			return "", fmt.Errorf("error: cannot segment '%s' at '%s' under %s\nHint: quote the Windows path or use forward slashes (e.g., C:/...)", tail, argHead(tail), curr)
+// create makes the directories r stopped short of for --create, and resolves r to the last of them.
+// With --check nothing is made; r still resolves to the path that would be.
+func create(r wslpath.Resolution) (wslpath.Resolution, error) {
+	for _, seg := range r.Missing {
+		if strings.ContainsAny(seg, "*?[") {
+			return r, fmt.Errorf("will not create %q beneath %s: it is a pattern, not a name", seg, r.Resolved)
 		}
+	}
+	p := filepath.Join(append([]string{r.Resolved}, r.Missing...)...)
+	if opts.check {
+		// --check writes nothing, so only say what would be made.
+		fmt.Fprintf(os.Stderr, "would create %s (not with --check)\n", p)
+		r.Resolved, r.Candidates, r.Unmatched, r.Missing = p, []string{p}, 0, nil
+		return r, nil
+	}
+	if err := os.MkdirAll(p, 0o755); err != nil {
+		return r, fmt.Errorf("cannot create %s: %v", p, err)
+	}
+	tracef(1, "created %s", p)
+	r.Resolved, r.Candidates, r.Unmatched, r.Missing = p, []string{p}, 0, nil
+	return r, nil
+}
 
-		sort.SliceStable(ms, func(i, j int) bool {
-			if ms[i].plen != ms[j].plen { return ms[i].plen > ms[j].plen }
-			if ms[i].score != ms[j].score { return ms[i].score > ms[j].score }
-			return ms[i].name < ms[j].name
-		})
+// needsCwd reports whether arg is relative to the current directory: a relative Linux path, or a Windows
+// path that is relative or rooted on the current drive.
+func needsCwd(arg, home string) bool {
+	if strings.HasPrefix(strings.TrimSpace(arg), "@") || opts.anyDrive || opts.label != "" {
+		return false
+	}
+	switch mode, _ := wslpath.DetectMode(arg, libOptions(home)); mode {
+	case wslpath.ModeRelative, wslpath.ModeRooted:
+		return true
+	case wslpath.ModeLinux:
+		// "~" and variables expand to absolute paths.
+		p := strings.Trim(strings.TrimSpace(arg), `"'`)
+		return !strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "~") && !strings.HasPrefix(p, "$")
+	}
+	return false
+}
+
+// rootFor returns the directory arg is relative to: --root for a Linux path, else cwd. Windows paths
+// keep the real cwd, and with it the current drive.
+func rootFor(arg, cwd, home string) string {
+	if opts.root == "" || strings.HasPrefix(strings.TrimSpace(arg), "@") || opts.anyDrive || opts.label != "" {
+		return cwd
+	}
+	if mode, _ := wslpath.DetectMode(arg, libOptions(home)); mode != wslpath.ModeLinux {
+		return cwd
+	}
+	return opts.root
+}
 
-		chosen := ms[0]
-		curr = filepath.Join(curr, chosen.name)
-		tail = tail[chosen.plen:]
+// absolute makes p absolute against cwd, as a safety net for --absolute.
+func absolute(p, cwd string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
 	}
+	tracef(1, "%s is not absolute; resolving it against %s", p, cwd)
+	return filepath.Join(cwd, p)
 }
 
-func argHead(s string) string {
-	if len(s) == 0 { return "" }
-	if len(s) > 16 { return s[:16] + "..." }
-	return s
+// canonical follows every symlink in p for --resolve-symlinks, including ones that lead off a mount
+// into the Linux filesystem, so the printed path names the real directory.
+func canonical(p string) (string, error) {
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve symlinks in %s: %v", p, err)
+	}
+	if real != p {
+		tracef(1, "%s is really %s", p, real)
+	}
+	return real, nil
+}
+
+// resolveArg resolves arg with the library, on every drive with --any, or beneath a volume with --by-label. @bookmarks are handled here since
+// those live in the user's config.
+func resolveArg(arg, cwd, home string) (wslpath.Resolution, error) {
+	if name, ok := strings.CutPrefix(strings.TrimSpace(arg), "@"); ok {
+		tracef(1, "input %q: bookmark path", arg)
+		p, err := resolveBookmark(name, home)
+		if err != nil {
+			return wslpath.Resolution{Input: arg, Mode: "bookmark"}, err
+		}
+		return wslpath.Resolution{Input: arg, Resolved: p, Mode: "bookmark", Candidates: []string{p}, Considered: 1}, nil
+	}
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+	if opts.anyDrive {
+		return wslpath.ResolveAnyDriveContext(ctx, arg, libOptions(home))
+	}
+	if opts.label != "" {
+		return wslpath.ResolveLabelContext(ctx, opts.label, arg, libOptions(home))
+	}
+	return wslpath.ResolveDetailedContext(ctx, arg, cwd, home, libOptions(home))
 }
 
-func pickCaseInsensitiveEntry(dir, want string) (string, error) {
-	ents, err := os.ReadDir(dir)
-	if err != nil { return "", err }
-	wantLower := strings.ToLower(want)
-	type pair struct { name string; score int }
-	var matches []pair
-	for _, e := range ents {
-		n := e.Name()
-		if strings.EqualFold(n, want) {
-			matches = append(matches, pair{name: n, score: caseScore(want, n)})
-		}
-	}
-	if len(matches) == 0 {
-		candidate := filepath.Join(dir, wantLower)
-		if st, err := os.Stat(candidate); err == nil && st.IsDir() { return wantLower, nil }
-		return "", fmt.Errorf("no match for %s in %s", want, dir)
-	}
-	sort.SliceStable(matches, func(i, j int) bool {
-		if matches[i].score != matches[j].score { return matches[i].score > matches[j].score }
-		return matches[i].name < matches[j].name
-	})
-	return matches[0].name, nil
+// normalize maps and cleans arg as if every name in it existed as typed, without looking at the
+// filesystem: a bookmark is replaced by its saved path, anything else goes to wslpath.Normalize.
+func normalize(arg, cwd, home string) string {
+	if name, ok := strings.CutPrefix(strings.TrimSpace(arg), "@"); ok {
+		bms, _ := loadBookmarks(home)
+		for _, bm := range bms {
+			if bm.name == name {
+				return bm.path
+			}
+		}
+	}
+	return wslpath.Normalize(arg, rootFor(arg, cwd, home), home, libOptions(home))
 }
 
-type candidate struct { fullPath string; score int }
-
-func exploreCandidates(root string, segs []string) ([]candidate, error) {
-	type state struct { dir string; idx int; score int }
-	var results []candidate
-	var dfs func(st state) error
-	dfs = func(st state) error {
-		if st.idx >= len(segs) {
-			info, err := os.Stat(st.dir)
-			if err != nil { return nil }
-			if info.IsDir() { results = append(results, candidate{fullPath: st.dir, score: st.score}) }
-			return nil
-		}
-		seg := segs[st.idx]
-		ents, err := os.ReadDir(st.dir)
-		if err != nil { return nil }
-		type match struct { name string; score int; path string }
-		var ms []match
-		for _, e := range ents {
-			n := e.Name()
-			if !strings.EqualFold(n, seg) { continue }
-			full := filepath.Join(st.dir, n)
-			isDir, err := isDirFollowSymlink(full, e)
-			if err != nil || !isDir { if st.idx == len(segs)-1 { continue }; continue }
-			ms = append(ms, match{name: n, score: caseScore(seg, n), path: full})
-		}
-		if len(ms) == 0 { return nil }
-		for _, m := range ms {
-			if err := dfs(state{dir: m.path, idx: st.idx + 1, score: st.score + m.score}); err != nil { return err }
-		}
-		return nil
-	}
-	if len(segs) == 0 {
-		if info, err := os.Stat(root); err == nil && info.IsDir() { results = append(results, candidate{fullPath: root, score: 0}) }
-		return results, nil
-	}
-	if err := dfs(state{dir: root, idx: 0, score: 0}); err != nil { return nil, err }
-	return results, nil
+// detectMode classifies arg the way resolveArg would resolve it, without touching the filesystem.
+func detectMode(arg, home string) (string, error) {
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(arg), "@"):
+		return "bookmark", nil
+	case opts.anyDrive:
+		return wslpath.ModeAny, nil
+	case opts.label != "":
+		return wslpath.ModeLabel, nil
+	}
+	return wslpath.DetectMode(arg, libOptions(home))
 }
 
-func isDirFollowSymlink(full string, de fs.DirEntry) (bool, error) {
-	if de.IsDir() { return true, nil }
-	info, err := os.Stat(full)
-	if err != nil { return false, err }
-	return info.IsDir(), nil
+// isTerminal reports whether f is a tty.
+func isTerminal(f *os.File) bool {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t)))
+	return errno == 0
 }
 
-func caseScore(input, candidate string) int {
-	inRunes := []rune(input)
-	cRunes := []rune(candidate)
-	n := len(inRunes)
-	if len(cRunes) < n { n = len(cRunes) }
-	score := 0
-	for i := 0; i < n; i++ { if inRunes[i] == cRunes[i] { score++ } }
-	return score
+// choose lists paths on stderr and reads a 1-based selection from the controlling tty.
+func choose(paths []string) (string, error) {
+	in := os.Stdin
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		defer tty.Close()
+		in = tty
+	}
+	for i, p := range paths {
+		fmt.Fprintf(os.Stderr, "%3d) %s\n", i+1, p)
+	}
+	fmt.Fprintf(os.Stderr, "Select [1-%d]: ", len(paths))
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no selection made: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(paths) {
+		return "", fmt.Errorf("invalid selection: %s", strings.TrimSpace(line))
+	}
+	return paths[n-1], nil
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wslcd/pkg/wslpath"
+)
+
+func TestJoinLines(t *testing.T) {
+	in := "C:\\Users\r\n\\me\r\n"
+	got, err := readTarget(strings.NewReader(in))
+	if want := "C:\\Users\r\n\\me"; err != nil || got != want {
+		t.Errorf("readTarget(%q) = %q, %v; want %q", in, got, err, want)
+	}
+	o, args, err := parseArgs([]string{"--join-lines", got}, options{})
+	if err != nil || !o.joinLines || len(args) != 1 || args[0] != got {
+		t.Errorf("parseArgs(--join-lines) = %+v, %q, %v", o, args, err)
+	}
+	opts = o
+	t.Cleanup(func() { opts = options{} })
+	if !libOptions(t.TempDir()).JoinLines {
+		t.Error("--join-lines does not set Options.JoinLines")
+	}
+}
+
+func TestDeletedCwd(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd); cwdErr = nil })
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gone := filepath.Join(t.TempDir(), "branch")
+	if err := os.Mkdir(gone, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(gone); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PWD", gone)
+
+	var cwd string
+	cwd, cwdErr = currentDir()
+	if cwdErr == nil || !strings.Contains(cwdErr.Error(), gone+" no longer exists") {
+		t.Fatalf("currentDir() = %q, %v; want an error naming %s", cwd, cwdErr, gone)
+	}
+	// A relative path falls back to HOME; an absolute one doesn't need the cwd.
+	if r, err := resolve("src", cwd, home); err != nil || r.Resolved != filepath.Join(home, "src") {
+		t.Errorf("resolve(src) = %q, %v; want %q", r.Resolved, err, filepath.Join(home, "src"))
+	}
+	if r, err := resolve(home, cwd, ""); err != nil || r.Resolved != home {
+		t.Errorf("resolve(%s) = %q, %v; want it unchanged", home, r.Resolved, err)
+	}
+	if _, err := resolve("src", cwd, ""); err == nil || !strings.Contains(err.Error(), "HOME is not set") {
+		t.Errorf("resolve(src) without HOME = %v; want an error about the cwd and HOME", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{wslpath.ErrNotFound, exitNotFound},
+		{fs.ErrNotExist, exitNotFound},
+		{wslpath.ErrNotDirectory, exitNotDir},
+		{wslpath.ErrDriveMapping, exitDriveMapping},
+		{wslpath.ErrUnsegmentable, exitUnsegmentable},
+		{wslpath.ErrAmbiguous, exitAmbiguous},
+		{wslpath.ErrTooDeep, exitTooDeep},
+		{wslpath.ErrNoTarget, exitUsage},
+		{context.DeadlineExceeded, exitFailure},
+		{errors.New("other"), exitFailure},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", tt.err)
+		if got := exitCode(err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d; want %d", err, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCreatesNothing(t *testing.T) {
+	base := t.TempDir()
+	opts = options{check: true, create: true}
+	t.Cleanup(func() { opts = options{} })
+	want := filepath.Join(base, "new/dir")
+	r, err := resolve(want, base, base)
+	if err != nil || r.Resolved != want {
+		t.Errorf("resolve(%s) with --check --create = %q, %v; want %q", want, r.Resolved, err, want)
+	}
+	if _, err := os.Stat(filepath.Join(base, "new")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("--check --create made %s (stat: %v)", filepath.Join(base, "new"), err)
+	}
+
+	opts.check = false
+	if r, err := resolve(want, base, base); err != nil || r.Resolved != want {
+		t.Errorf("resolve(%s) with --create = %q, %v; want %q", want, r.Resolved, err, want)
+	}
+	if info, err := os.Stat(want); err != nil || !info.IsDir() {
+		t.Errorf("--create did not make %s: %v", want, err)
+	}
+}
+
+func TestJSONMaxResults(t *testing.T) {
+	base := t.TempDir()
+	for _, d := range []string{"proj-a", "proj-b", "proj-c"} {
+		if err := os.Mkdir(filepath.Join(base, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts = options{json: true, maxResults: 1}
+	t.Cleanup(func() { opts = options{} })
+	// An ambiguous glob is listed, not an error, once --max-results asks for the best of it.
+	r, err := resolve(filepath.Join(base, "proj-*"), base, base)
+	if err != nil {
+		t.Fatalf("resolve(proj-*) with --json --max-results 1: %v", err)
+	}
+	truncated := limitCandidates(&r)
+	b, err := json.Marshal(jsonResult{Resolution: r, Truncated: truncated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"truncated":true`) || len(r.Candidates) != 1 || r.Candidates[0] != r.Resolved {
+		t.Errorf("--json --max-results 1 on proj-* = %s; want the best of three candidates, truncated", b)
+	}
+}
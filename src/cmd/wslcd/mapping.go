@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wslcd/pkg/wslpath"
+)
+
+// showMapping prints how drive (e.g. "C" or "C:") is mapped to a directory and returns the exit code:
+// 0 if the mapping is a directory.
+func showMapping(drive, home string) int {
+	letter := strings.TrimRight(drive, ":\\/")
+	if len(letter) != 1 || !isLetter(letter[0]) {
+		fmt.Fprintf(os.Stderr, "error: --show-mapping wants a drive letter, got %q\n", drive)
+		return exitUsage
+	}
+	letter = strings.ToUpper(letter)
+	o := libOptions(home)
+	roots, source := mountRoots()
+	fmt.Printf("drive:      %s:\n", letter)
+	fmt.Printf("mount root: %s (%s)\n", strings.Join(roots, ", "), source)
+	if o.Wslpath != "" {
+		fmt.Printf("wslpath:    %s\n", o.Wslpath)
+	}
+
+	p, err := wslpath.MapDrive(letter[0], o)
+	if err != nil {
+		fmt.Printf("mapped to:  (none)\n")
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitCode(err)
+	}
+	fmt.Printf("mapped to:  %s\n", p)
+	info, err := os.Stat(p)
+	fmt.Printf("exists:     %s\n", yesNo(err == nil))
+	fmt.Printf("directory:  %s\n", yesNo(err == nil && info.IsDir()))
+	switch {
+	case err != nil:
+		return exitDriveMapping
+	case !info.IsDir():
+		return exitNotDir
+	}
+	return 0
+}
+
+// mappedWindowsPath gives the Windows form of p if it is in a directory WSLCD_DRIVE_MAP maps a drive to,
+// e.g. "Z:\\docs" for /srv/shared/docs with z=/srv/shared. The deepest such directory wins.
+func mappedWindowsPath(p string) (string, bool) {
+	best, win := "", ""
+	for letter, dir := range driveMap() {
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") || len(dir) <= len(best) {
+			continue
+		}
+		best, win = dir, strings.ToUpper(string(letter))+":\\"+strings.ReplaceAll(strings.TrimPrefix(rel, "."), "/", "\\")
+	}
+	return win, best != ""
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,29 @@
+package main
+
+// subcommands maps each subcommand to the options it stands for, e.g. "wslcd doctor" is "wslcd --doctor".
+// "resolve" is what a bare path does.
+var subcommands = map[string][]string{
+	"resolve":    nil,
+	"windows":    {"--to-windows"},
+	"bookmark":   {"--bookmark"},
+	"bookmarks":  {"--list-bookmarks"},
+	"history":    {"--history"},
+	"completion": {"--completion"},
+	"wrapper":    {"--wrapper"},
+	"mapping":    {"--show-mapping"},
+	"doctor":     {"--doctor"},
+}
+
+// expandSubcommand rewrites args starting with a subcommand into the equivalent options, leaving the
+// rest of the arguments after them. Anything else, including a bare path, is returned unchanged; a
+// directory named like a subcommand is reached as ./name.
+func expandSubcommand(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	flags, ok := subcommands[args[0]]
+	if !ok {
+		return args
+	}
+	return append(append([]string{}, flags...), args[1:]...)
+}
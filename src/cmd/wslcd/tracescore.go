@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"wslcd/pkg/wslpath"
+)
+
+// traceScoreMax is how many of the best candidates --trace-score shows.
+const traceScoreMax = 5
+
+// traceScore writes the best candidates of r as a table for --trace-score: each matched segment as typed
+// and as named with its case score split into its parts (exact match, longest exact-case run, exact-case
+// positions less the length difference), and the rule that ranked the candidate above the next one.
+func traceScore(w io.Writer, r wslpath.Resolution) {
+	if len(r.Ranked) == 0 {
+		fmt.Fprintln(w, "trace-score: no scored candidates (only Windows path walks are scored)")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tcandidate\tsegment\tscore\texact\trun\tpos\tfuzz\tranked above next by")
+	for i, c := range r.Ranked {
+		decision := c.Decision
+		if decision == "" {
+			decision = "-"
+		}
+		if len(c.Scores) == 0 {
+			fmt.Fprintf(tw, "%d\t%s\t-\t0\t\t\t\t%d\t%s\n", i+1, c.Path, c.Fuzz, decision)
+		}
+		for j, s := range c.Scores {
+			exact, run, pos := wslpath.SplitCaseScore(s)
+			num, path := "", ""
+			if j == 0 {
+				num, path = fmt.Sprint(i+1), c.Path
+			}
+			fuzz, dec := "", ""
+			if j == len(c.Scores)-1 {
+				fuzz, dec = fmt.Sprint(c.Fuzz), decision
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%d\t%d\t%s\t%s\n", num, path, segmentPair(c.Typed[j], c.Names[j]), s, yesNo(exact), run, pos, fuzz, dec)
+		}
+	}
+	tw.Flush()
+}
+
+// segmentPair shows a segment as typed and the name it matched, or just once when they are identical.
+func segmentPair(typed, name string) string {
+	if typed == name {
+		return typed
+	}
+	return typed + " -> " + name
+}
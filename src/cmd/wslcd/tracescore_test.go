@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wslcd/pkg/wslpath"
+)
+
+func TestTraceScoreExactInput(t *testing.T) {
+	mnt := t.TempDir()
+	for _, d := range []string{"c/Users/me/Docs", "c/Users/ME/docs"} {
+		if err := os.MkdirAll(filepath.Join(mnt, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("WSLCD_MNT_ROOT", mnt)
+	opts = options{traceScore: true}
+	t.Cleanup(func() { opts = options{} })
+	r, err := wslpath.ResolveDetailed(`C:\Users\me\Docs`, "/", "/", libOptions("/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	traceScore(&b, r)
+	rows := 0
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, "1 ") || strings.HasPrefix(line, "2 ") {
+			rows++
+		}
+	}
+	if rows < 2 {
+		t.Errorf("--trace-score for an exact-case input printed %d candidates:\n%s", rows, b.String())
+	}
+}
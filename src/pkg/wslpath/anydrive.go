@@ -0,0 +1,121 @@
+package wslpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ResolveAnyDrive resolves rel, a Windows path without a drive such as "Projects\MyRepo", by walking it
+// beneath every drive under the mount roots. It succeeds if exactly one drive has a match; matches on
+// several drives go to Options.Choose, or are listed in the error.
+func ResolveAnyDrive(rel string, opts Options) (Resolution, error) {
+	return ResolveAnyDriveContext(context.Background(), rel, opts)
+}
+
+// ResolveAnyDriveContext is ResolveAnyDrive, giving up on walking the filesystem when ctx is done.
+func ResolveAnyDriveContext(ctx context.Context, rel string, opts Options) (Resolution, error) {
+	return newResolver(ctx, "", "", opts).resolveAnyDrive(rel)
+}
+
+func (rs *resolver) resolveAnyDrive(input string) (Resolution, error) {
+	res := Resolution{Input: input, Mode: ModeAny}
+	segs := windowsSegments(unquote(strings.TrimSpace(input)))
+	if len(segs) == 0 {
+		return res, ErrNoTarget
+	}
+	roots := rs.mountRoots()
+	drives, err := rs.listDrives(roots)
+	if err != nil {
+		return res, err
+	}
+
+	// Keep the best match per drive; several drives matching is the ambiguity the caller resolves.
+	var best []candidate
+	for _, root := range drives {
+		n := filepath.Base(root)
+		cands, _, err := rs.exploreCandidates(root, segs)
+		if err != nil || len(cands) == 0 {
+			continue
+		}
+		rs.sortCandidates(cands)
+		rs.tracef(1, "drive %s: %d matches, best %s", strings.ToUpper(n), len(cands), cands[0].fullPath)
+		res.Considered += len(cands)
+		best = append(best, cands[0])
+	}
+	if len(best) == 0 {
+		return res, fmt.Errorf("%w on any drive under %s: %s", ErrNotFound, strings.Join(roots, " or "), input)
+	}
+
+	for _, c := range best {
+		res.Candidates = append(res.Candidates, c.fullPath)
+	}
+	p, err := rs.pickOne(input, res.Candidates)
+	if err == nil {
+		p, err = rs.verifyDir(p)
+	}
+	if err != nil {
+		return res, err
+	}
+	res.Resolved = p
+	for _, c := range best {
+		if c.fullPath == p {
+			res.Score = c.score
+		}
+	}
+	return res, nil
+}
+
+// listDrives returns the directories of the drives under roots: single-letter directories, with a drive
+// found under an earlier root hiding the same letter under later ones. Letters in Options.ExcludeDrives
+// are left out, whatever their case. Drives in Options.DriveMap come first, from their mapped directories.
+func (rs *resolver) listDrives(roots []string) ([]string, error) {
+	var drives []string
+	var errs []string
+	seen := map[rune]bool{}
+	var mapped []byte
+	for letter := range rs.opts.DriveMap {
+		mapped = append(mapped, letter)
+	}
+	slices.Sort(mapped)
+	for _, letter := range mapped {
+		p := rs.opts.DriveMap[letter]
+		if strings.ContainsRune(strings.ToLower(rs.opts.ExcludeDrives), rune(letter)) {
+			continue
+		}
+		if info, err := rs.fs.stat(p); err == nil && info.IsDir() {
+			seen[rune(letter)] = true
+			drives = append(drives, p)
+		}
+	}
+	for _, mnt := range roots {
+		ents, err := rs.fs.readDir(mnt)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("cannot read directory %s: %v", mnt, err))
+			continue
+		}
+		for _, e := range ents {
+			n := e.Name()
+			if len(n) != 1 || !unicode.IsLetter(rune(n[0])) || seen[unicode.ToLower(rune(n[0]))] {
+				continue
+			}
+			if strings.ContainsRune(strings.ToLower(rs.opts.ExcludeDrives), unicode.ToLower(rune(n[0]))) {
+				rs.tracef(1, "drive %s: excluded", strings.ToUpper(n))
+				continue
+			}
+			if isDir, err := rs.isDirFollowSymlink(filepath.Join(mnt, n), e); err != nil || !isDir {
+				continue
+			}
+			seen[unicode.ToLower(rune(n[0]))] = true
+			drives = append(drives, filepath.Join(mnt, n))
+		}
+	}
+	if len(errs) == len(roots) {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return drives, nil
+}
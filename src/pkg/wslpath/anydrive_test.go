@@ -0,0 +1,57 @@
+package wslpath
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExcludeDrives(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Projects/App", "d/Projects/App", "E/Projects/App", "z/Projects/App")
+	mapped := mkdirs(t, t.TempDir(), "Projects/App")
+	tests := []struct {
+		exclude string
+		want    []string
+	}{
+		{"", []string{"E", "c", "d", "z"}}, // in directory order
+		{"d", []string{"E", "c", "z"}},
+		{"De", []string{"c", "z"}}, // letters in either case
+		{"cdez", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		opts := Options{MountRoot: mnt, ExcludeDrives: tt.exclude, Choose: func(paths []string) (string, error) {
+			got = paths
+			return paths[0], nil
+		}}
+		r, err := ResolveAnyDrive(`projects\app`, opts)
+		if len(tt.want) == 0 {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("ResolveAnyDrive excluding %q = %q, %v; want ErrNotFound", tt.exclude, r.Resolved, err)
+			}
+			continue
+		}
+		if len(tt.want) == 1 {
+			got = r.Candidates
+		}
+		var want []string
+		for _, d := range tt.want {
+			want = append(want, filepath.Join(mnt, d, "Projects/App"))
+		}
+		if err != nil || !slices.Equal(got, want) || !slices.Equal(r.Candidates, want) {
+			t.Errorf("ResolveAnyDrive excluding %q = candidates %q, chose among %q, %v; want %q", tt.exclude, r.Candidates, got, err, want)
+		}
+	}
+
+	// A drive that is mapped elsewhere is excluded all the same.
+	r, err := ResolveAnyDrive(`projects\app`, Options{MountRoot: mnt, ExcludeDrives: "cdez", DriveMap: map[byte]string{'m': mapped, 'z': mapped}})
+	if want := filepath.Join(mapped, "Projects/App"); err != nil || r.Resolved != want || len(r.Candidates) != 1 {
+		t.Errorf("ResolveAnyDrive with mapped drives = %q %q, %v; want only %q", r.Resolved, r.Candidates, err, want)
+	}
+
+	// An explicit drive is never excluded.
+	if got, err := ResolveTarget(`D:\projects\app`, "/", "/", Options{MountRoot: mnt, ExcludeDrives: "d"}); err != nil || got != filepath.Join(mnt, "d/Projects/App") {
+		t.Errorf("ResolveTarget(D:\\projects\\app) excluding d = %q, %v", got, err)
+	}
+}
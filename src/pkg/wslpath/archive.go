@@ -0,0 +1,82 @@
+package wslpath
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveExts are the file extensions Options.ThroughArchives looks for a mount of, longest first.
+var archiveExts = []string{".tar.gz", ".tar.bz2", ".tar.xz", ".zip", ".7z", ".rar", ".tar", ".tgz", ".iso"}
+
+// mountInfoPath lists the mounts of this process.
+const mountInfoPath = "/proc/self/mountinfo"
+
+// archiveExt returns the archive extension of name, or "" if it has none.
+func archiveExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lower, ext) && len(lower) > len(ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// archiveMount returns the directory an archive file p is mounted on by a FUSE tool such as ratarmount,
+// fuse-zip or archivemount, for Options.ThroughArchives: p itself, or p without its extension as those
+// tools name the mount point by default. ok is false if neither is a FUSE mount point.
+func (rs *resolver) archiveMount(p string) (dir string, ok bool) {
+	ext := archiveExt(filepath.Base(p))
+	if ext == "" {
+		return "", false
+	}
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		rs.tracef(1, "cannot list mounts for %s: %v", p, err)
+		return "", false
+	}
+	defer f.Close()
+	want := map[string]bool{p: true, p[:len(p)-len(ext)]: true}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// id parent major:minor root mountpoint options [optional...] - fstype source superoptions
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		mnt := unescapeMountPath(fields[4])
+		if !want[mnt] {
+			continue
+		}
+		for i, fld := range fields {
+			if fld == "-" && i+1 < len(fields) && strings.HasPrefix(fields[i+1], "fuse") {
+				rs.tracef(1, "archive %s is mounted on %s", p, mnt)
+				return mnt, true
+			}
+		}
+	}
+	return "", false
+}
+
+// unescapeMountPath undoes the octal escapes (\040 for a space) of a mount point in mountinfo.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b.WriteByte((s[i+1]-'0')<<6 | (s[i+2]-'0')<<3 | (s[i+3] - '0'))
+			i += 3
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
@@ -0,0 +1,121 @@
+package wslpath
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// dirCache memoizes directory listings and stat results for the lifetime of one resolver, so tied
+// branches and collapsed segmentation list each directory at most once. Nothing outlives the resolution.
+//
+// Stat results follow symlinks; whether an entry is itself a symlink comes from its cached fs.DirEntry,
+// so Options.NoFollowSymlinks sees the same information with or without the cache.
+//
+// Once ctx is done, uncached lookups fail with its error instead of waiting on a slow mount.
+//
+// The cache is safe for concurrent use, so prefetch can fill it from several goroutines.
+type dirCache struct {
+	ctx   context.Context
+	mu    sync.Mutex
+	lists map[string]dirList
+	stats map[string]statResult
+}
+
+type dirList struct {
+	ents []fs.DirEntry
+	err  error
+}
+
+type statResult struct {
+	info fs.FileInfo
+	err  error
+}
+
+func newDirCache(ctx context.Context) *dirCache {
+	return &dirCache{ctx: ctx, lists: map[string]dirList{}, stats: map[string]statResult{}}
+}
+
+// readDir is os.ReadDir, listing each directory once.
+func (c *dirCache) readDir(dir string) ([]fs.DirEntry, error) {
+	c.mu.Lock()
+	l, ok := c.lists[dir]
+	c.mu.Unlock()
+	if ok {
+		return l.ents, l.err
+	}
+	if err := c.wait(func() { l.ents, l.err = os.ReadDir(dir) }); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.lists[dir] = l
+	c.mu.Unlock()
+	return l.ents, l.err
+}
+
+// stat is os.Stat, statting each path once.
+func (c *dirCache) stat(p string) (fs.FileInfo, error) {
+	c.mu.Lock()
+	s, ok := c.stats[p]
+	c.mu.Unlock()
+	if ok {
+		return s.info, s.err
+	}
+	if err := c.wait(func() { s.info, s.err = os.Stat(p) }); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.stats[p] = s
+	c.mu.Unlock()
+	return s.info, s.err
+}
+
+// prefetch lists dirs and stats files on up to workers goroutines and returns once all are cached, so a
+// walk that visits them next doesn't wait on each in turn. Only the I/O runs concurrently; the walk itself
+// stays serial, and so its result doesn't depend on the number of workers.
+func (c *dirCache) prefetch(dirs, files []string, workers int) {
+	if workers <= 1 || len(dirs)+len(files) <= 1 {
+		return
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			f()
+		}()
+	}
+	for _, d := range dirs {
+		run(func() { c.readDir(d) })
+	}
+	for _, f := range files {
+		run(func() { c.stat(f) })
+	}
+	wg.Wait()
+}
+
+// wait runs f, returning early with the context's error if it is done first. f is then left to
+// finish in the background and its result is discarded.
+func (c *dirCache) wait(f func()) error {
+	if c.ctx.Done() == nil {
+		f()
+		return nil
+	}
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
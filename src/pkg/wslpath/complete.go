@@ -0,0 +1,88 @@
+package wslpath
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// Complete returns completions for a partially typed Windows path, matching names case-insensitively
+// like the resolvers do. Other input gets no completions so a shell can fall back to its own.
+func Complete(word string, opts Options) []string {
+	head, names, sep := newResolver(context.Background(), "", "", opts).complete(word)
+	var out []string
+	for _, n := range names {
+		out = append(out, head+n+sep)
+	}
+	return out
+}
+
+// CompleteNames returns the names of the directories that could continue a partially typed Windows path,
+// e.g. "me" and "Max" for `C:\Users\m`. The last component of word need not exist.
+func CompleteNames(word string, opts Options) []string {
+	_, names, _ := newResolver(context.Background(), "", "", opts).complete(word)
+	return names
+}
+
+// complete splits the completions of word into the text before the last component, the names that
+// could complete it, and the separator that follows a completed name.
+func (rs *resolver) complete(word string) (head string, names []string, sep string) {
+	word = strings.TrimLeft(word, `"'`)
+	switch {
+	case IsWindowsPath(word):
+		k := strings.LastIndexAny(word, `\/`)
+		if k < 0 {
+			return "", nil, ""
+		}
+		head, partial := word[:k+1], word[k+1:]
+		r, err := rs.resolveWindowsPath(head)
+		if err != nil {
+			return "", nil, ""
+		}
+		return head, rs.completeIn(r.Resolved, partial), word[k : k+1]
+	case IsCollapsedWindowsPath(word):
+		// Segment the collapsed tail as far as it goes, like resolveWindowsPathCollapsed,
+		// then complete the rest; the result is spelled out with separators.
+		curr, err := rs.mapDrive(word[0])
+		if err != nil {
+			return "", nil, ""
+		}
+		head := strings.ToUpper(word[:1]) + ":/"
+		tail := strings.TrimLeft(word[2:], `\/`)
+		for tail != "" {
+			ms, err := rs.collapsedMatches(curr, tail, false)
+			if err != nil || len(ms) == 0 {
+				break
+			}
+			tail = strings.TrimLeft(tail[ms[0].plen:], `\/`)
+			if tail == "" {
+				// Fully segmented: the last name completes the word.
+				return head, []string{ms[0].name}, "/"
+			}
+			curr = filepath.Join(curr, ms[0].name)
+			head += ms[0].name + "/"
+		}
+		return head, rs.completeIn(curr, tail), "/"
+	}
+	return "", nil, ""
+}
+
+// completeIn returns the names of the directories in dir that start with partial, ignoring case.
+func (rs *resolver) completeIn(dir, partial string) []string {
+	ents, err := rs.fs.readDir(dir)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range ents {
+		n := e.Name()
+		if rs.namePrefix(n, partial) < 0 || rs.ignored(n) {
+			continue
+		}
+		if isDir, err := rs.isDirFollowSymlink(filepath.Join(dir, n), e); err != nil || !isDir {
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
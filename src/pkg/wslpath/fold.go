@@ -0,0 +1,51 @@
+package wslpath
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// foldRune maps r to a canonical rune shared by all its case variants, following Unicode simple case
+// folding, so "É" and "é" (or "K" and the Kelvin sign) fold alike. The Turkish dotted İ and dotless ı
+// fold with plain i, since a name typed on one keyboard layout should match one created on another.
+func foldRune(r rune) rune {
+	if r == 'ı' || r == 'İ' {
+		r = 'i'
+	}
+	// The smallest rune in the fold orbit stands for all of them.
+	canon := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < canon {
+			canon = f
+		}
+	}
+	return canon
+}
+
+// foldString applies foldRune to every rune of s.
+func foldString(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+// foldEqual reports whether a and b are equal under foldRune.
+func foldEqual(a, b string) bool {
+	return foldPrefix(a, b) == len(a)
+}
+
+// foldPrefix returns the length in bytes of the prefix of s that equals prefix under foldRune, or -1 if
+// s doesn't start with it. Case variants may differ in encoded length, so this is not always len(prefix).
+func foldPrefix(s, prefix string) int {
+	i := 0
+	for _, pr := range prefix {
+		if i >= len(s) {
+			return -1
+		}
+		sr, size := utf8.DecodeRuneInString(s[i:])
+		if foldRune(sr) != foldRune(pr) {
+			return -1
+		}
+		i += size
+	}
+	return i
+}
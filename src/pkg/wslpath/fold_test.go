@@ -0,0 +1,49 @@
+package wslpath
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUnicodeNames(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Café/Menu", "c/Dır/İstanbul", "d/Café", "d/CAFÉ")
+	tests := []struct{ in, want string }{
+		{`C:\café\menu`, "c/Café/Menu"},
+		{`C:\CAFÉ\MENU`, "c/Café/Menu"},
+		{`C:CAFÉMENU`, "c/Café/Menu"}, // collapsed
+		// Dotted and dotless i match plain i either way round.
+		{`C:\dir\istanbul`, "c/Dır/İstanbul"},
+		{`C:\DIR\ISTANBUL`, "c/Dır/İstanbul"},
+		{`C:\dır\ıstanbul`, "c/Dır/İstanbul"},
+		// Both spellings exist: the closer case wins, by rune rather than by byte.
+		{`D:\cafÉ`, "d/Café"},
+		{`D:\CAFé`, "d/CAFÉ"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveTarget(tt.in, "/", "/", Options{MountRoot: mnt})
+		if want := filepath.Join(mnt, tt.want); err != nil || got != want {
+			t.Errorf("ResolveTarget(%q) = %q, %v; want %q", tt.in, got, err, want)
+		}
+	}
+	if got, err := ResolveTarget(`C:\CAFÉ`, "/", "/", Options{MountRoot: mnt, CaseSensitive: true}); err == nil {
+		t.Errorf("ResolveTarget(C:\\CAFÉ) with CaseSensitive = %q; want no match", got)
+	}
+}
+
+func TestFoldPrefix(t *testing.T) {
+	tests := []struct {
+		s, prefix string
+		want      int
+	}{
+		{"CAFÉMENU", "café", len("CAFÉ")},
+		{"straße", "STRASSE", -1}, // simple folding maps rune to rune
+		{"ıi", "II", len("ıi")},
+		{"KELVIN", "\u212aelvin", len("KELVIN")}, // Kelvin sign
+		{"Caf", "Café", -1},
+	}
+	for _, tt := range tests {
+		if got := foldPrefix(tt.s, tt.prefix); got != tt.want {
+			t.Errorf("foldPrefix(%q, %q) = %d; want %d", tt.s, tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package wslpath
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fuzzyLimit caps how many fuzzy matches are followed per segment, so a typo near the root
+// doesn't fan out into every sibling directory.
+const fuzzyLimit = 3
+
+type fuzzyMatch struct {
+	name  string
+	dist  int
+	score int
+	link  bool // the entry is a symlink
+}
+
+// fuzzyMatches returns up to fuzzyLimit directory entries of dir that approximately match seg: either seg is a
+// case-insensitive subsequence of the name (e.g. "prj" in "Projects") or the name is within a small edit distance.
+// Matches are ordered by distance, then CaseScore, then name. last is set for the final path segment.
+func (rs *resolver) fuzzyMatches(dir, seg string, ents []fs.DirEntry, last bool) []fuzzyMatch {
+	maxDist := len([]rune(seg)) / 3
+	if maxDist < 1 {
+		maxDist = 1
+	}
+	var ms []fuzzyMatch
+	for _, e := range ents {
+		n := e.Name()
+		if rs.ignored(n) {
+			continue
+		}
+		d := editDistance(foldString(seg), foldString(n))
+		if d > maxDist && !isSubsequence(seg, n) {
+			continue
+		}
+		if !last && !rs.canDescend(e) {
+			continue
+		}
+		if isDir, err := rs.isDirFollowSymlink(filepath.Join(dir, n), e); err != nil || !isDir {
+			continue
+		}
+		ms = append(ms, fuzzyMatch{name: n, dist: d, score: CaseScore(seg, n), link: e.Type()&fs.ModeSymlink != 0})
+	}
+	sort.SliceStable(ms, func(i, j int) bool {
+		if ms[i].dist != ms[j].dist { return ms[i].dist < ms[j].dist }
+		if ms[i].score != ms[j].score { return ms[i].score > ms[j].score }
+		return ms[i].name < ms[j].name
+	})
+	if len(ms) > fuzzyLimit {
+		ms = ms[:fuzzyLimit]
+	}
+	return ms
+}
+
+// isSubsequence reports whether the runes of needle appear in order in hay, ignoring case.
+func isSubsequence(needle, hay string) bool {
+	n := []rune(foldString(needle))
+	i := 0
+	for _, r := range foldString(hay) {
+		if i < len(n) && n[i] == r {
+			i++
+		}
+	}
+	return i == len(n)
+}
+
+// editDistance returns the edit distance between a and b in runes, counting insertions, deletions,
+// substitutions and transpositions of adjacent runes (a common typo) as one edit each.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// suggestLimit caps how many names a suggestion hint offers.
+const suggestLimit = 3
+
+// suggestHint returns a hint naming the directories in dir closest to seg by edit distance, for
+// Options.Suggest after seg matched nothing there, or "" if none is close. With prefix set, seg may run on
+// into further names, as in a collapsed path, so each name is compared with the start of seg.
+func (rs *resolver) suggestHint(dir, seg string, prefix bool) string {
+	if !rs.opts.Suggest || seg == "" {
+		return ""
+	}
+	ents, err := rs.fs.readDir(dir)
+	if err != nil {
+		return ""
+	}
+	want := []rune(foldString(seg))
+	maxDist := max(2, len(want)/3)
+	type suggestion struct {
+		name string
+		dist int
+	}
+	var ss []suggestion
+	for _, e := range ents {
+		n := e.Name()
+		if rs.ignored(n) {
+			continue
+		}
+		if isDir, err := rs.isDirFollowSymlink(filepath.Join(dir, n), e); err != nil || !isDir {
+			continue
+		}
+		name := foldString(n)
+		d := editDistance(string(want), name)
+		if prefix {
+			// The name may have been typed a rune shorter or longer than it is.
+			k := len([]rune(name))
+			for _, l := range []int{k - 1, k, k + 1} {
+				if l > 0 && l <= len(want) {
+					d = min(d, editDistance(string(want[:l]), name))
+				}
+			}
+		}
+		if d <= maxDist {
+			ss = append(ss, suggestion{n, d})
+		}
+	}
+	if len(ss) == 0 {
+		return ""
+	}
+	sort.SliceStable(ss, func(i, j int) bool {
+		if ss[i].dist != ss[j].dist {
+			return ss[i].dist < ss[j].dist
+		}
+		return ss[i].name < ss[j].name
+	})
+	var names []string
+	for _, s := range ss[:min(len(ss), suggestLimit)] {
+		names = append(names, "'"+s.name+"'")
+	}
+	return fmt.Sprintf("\nHint: did you mean %s in %s?", strings.Join(names, " or "), dir)
+}
@@ -0,0 +1,59 @@
+package wslpath
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hasGlobMeta reports whether s contains any filepath.Match metacharacters.
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// globMatch reports whether name matches the glob pattern, ignoring case like the other matchers
+// unless Options.CaseSensitive is set.
+func (rs *resolver) globMatch(pattern, name string) bool {
+	if !rs.opts.CaseSensitive {
+		pattern, name = foldString(pattern), foldString(name)
+	}
+	ok, err := filepath.Match(pattern, name)
+	return err == nil && ok
+}
+
+// expandGlob expands a Linux path containing glob metacharacters to the single directory it matches,
+// also returning every directory it matched. A path that exists literally is returned unchanged.
+func (rs *resolver) expandGlob(p string) (string, []string, error) {
+	if _, err := os.Stat(p); err == nil {
+		return p, nil, nil
+	}
+	matches, err := filepath.Glob(p)
+	if err != nil {
+		return "", nil, fmt.Errorf("bad pattern %s: %v", p, err)
+	}
+	var dirs []string
+	for _, m := range matches {
+		if info, err := rs.fs.stat(m); err == nil && (info.IsDir() || (rs.opts.Parent && info.Mode().IsRegular())) {
+			dirs = append(dirs, m)
+		}
+	}
+	rs.tracef(1, "glob %s matched %d directories", p, len(dirs))
+	if len(dirs) == 0 {
+		return "", nil, fmt.Errorf("%w: no directory matches %s", ErrNotFound, p)
+	}
+	pick, err := rs.pickOne(p, dirs)
+	return pick, dirs, err
+}
+
+// pickOne returns the only path in paths. Several paths are ambiguous: Options.Choose picks one if set,
+// otherwise they are listed in the error.
+func (rs *resolver) pickOne(pattern string, paths []string) (string, error) {
+	if len(paths) == 1 {
+		return paths[0], nil
+	}
+	if rs.opts.Choose != nil {
+		return rs.opts.Choose(paths)
+	}
+	return "", fmt.Errorf("%w: %s matches %d directories:\n  %s", ErrAmbiguous, pattern, len(paths), strings.Join(paths, "\n  "))
+}
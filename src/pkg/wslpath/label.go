@@ -0,0 +1,58 @@
+package wslpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveLabel resolves rel, a Windows path without a drive such as "Photos\2024", beneath the volume
+// mounted under a mount root by its label, e.g. "/mnt/My USB" for label "my usb". The label is matched
+// case-insensitively as a whole name, spaces included; an empty rel resolves to the volume itself.
+func ResolveLabel(label, rel string, opts Options) (Resolution, error) {
+	return ResolveLabelContext(context.Background(), label, rel, opts)
+}
+
+// ResolveLabelContext is ResolveLabel, giving up on walking the filesystem when ctx is done.
+func ResolveLabelContext(ctx context.Context, label, rel string, opts Options) (Resolution, error) {
+	return newResolver(ctx, "", "", opts).resolveLabel(label, rel)
+}
+
+func (rs *resolver) resolveLabel(label, input string) (Resolution, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return Resolution{Input: input, Mode: ModeLabel}, errors.New("missing volume label")
+	}
+	vol, err := rs.mapLabel(label)
+	if err != nil {
+		return Resolution{Input: input, Mode: ModeLabel}, err
+	}
+	res, err := rs.resolveSegments(vol, windowsSegments(unquote(strings.TrimSpace(input))), input)
+	res.Input, res.Mode = input, ModeLabel
+	if err == nil && len(res.Candidates) == 0 {
+		res.Candidates, res.Considered = []string{res.Resolved}, 1
+	}
+	return res, err
+}
+
+// mapLabel locates the directory a volume is mounted on by its label, under the first mount root that has it.
+func (rs *resolver) mapLabel(label string) (string, error) {
+	roots := rs.mountRoots()
+	var errs []string
+	for _, mnt := range roots {
+		name, err := rs.pickCaseInsensitiveEntry(mnt, label)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		p := filepath.Join(mnt, name)
+		if info, err := rs.fs.stat(p); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Sprintf("%s is not a directory", p))
+			continue
+		}
+		return p, nil
+	}
+	return "", fmt.Errorf("cannot locate volume %q under %s (%w): %s", label, strings.Join(roots, " or "), ErrDriveMapping, strings.Join(errs, "; "))
+}
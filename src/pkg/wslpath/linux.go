@@ -0,0 +1,293 @@
+package wslpath
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unicode"
+)
+
+// resolveLinux resolves a Linux path and verifies it names a directory.
+func (rs *resolver) resolveLinux(arg string) (Resolution, error) {
+	// An absolute path to an existing directory needs no expansion, joining or matching.
+	if strings.HasPrefix(arg, "/") && !strings.ContainsRune(arg, '$') && !hasGlobMeta(arg) && !rs.opts.Physical && !rs.opts.NoFollowSymlinks {
+		p := filepath.Clean(arg)
+		if info, err := rs.fs.stat(p); err == nil && info.IsDir() {
+			rs.tracef(1, "%s: exact path, no resolution needed", p)
+			return Resolution{Resolved: p}, nil
+		}
+	}
+	p, err := rs.resolveLinuxLike(arg)
+	if err != nil {
+		return Resolution{}, err
+	}
+	rs.tracef(1, "linux path cleaned to %s", p)
+	var matched []string
+	if hasGlobMeta(p) {
+		if p, matched, err = rs.expandGlob(p); err != nil {
+			return Resolution{}, err
+		}
+	}
+	if rs.opts.NoFollowSymlinks {
+		if err := checkNoSymlinks(p); err != nil {
+			return Resolution{}, err
+		}
+	}
+	d, err := rs.verifyDir(p)
+	if errors.Is(err, fs.ErrNotExist) && len(rs.opts.SearchPath) > 0 && searchable(arg) {
+		if r, ok := rs.searchBases(arg); ok {
+			return r, nil
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) && rs.opts.Nearest {
+		// Climb to the deepest ancestor that exists.
+		anc, missing := p, []string(nil)
+		for err != nil && anc != "/" {
+			missing = append([]string{filepath.Base(anc)}, missing...)
+			anc = filepath.Dir(anc)
+			d, err = rs.verifyDir(anc)
+		}
+		rs.tracef(1, "nearest existing ancestor of %s is %s", p, d)
+		return Resolution{Resolved: d, Unmatched: len(missing), Missing: missing}, err
+	}
+	return Resolution{Resolved: d, Candidates: matched}, err
+}
+
+// homeDir returns the home directory "~" stands for: HOME, or when that is empty (as under some sudo and
+// login setups) the current user's home directory from the user database.
+func (rs *resolver) homeDir() (string, error) {
+	if rs.home != "" {
+		return rs.home, nil
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("HOME is not set and the user database lookup failed: %v", err)
+	}
+	if u.HomeDir == "" {
+		return "", fmt.Errorf("HOME is not set and user %s has no home directory", u.Username)
+	}
+	rs.tracef(1, "HOME is not set; using %s from the user database", u.HomeDir)
+	return u.HomeDir, nil
+}
+
+// searchable reports whether arg may be looked up in Options.SearchPath: like a name looked up in CDPATH,
+// it is relative and does not start with "." or "..".
+func searchable(arg string) bool {
+	first, _, _ := strings.Cut(arg, "/")
+	return first != "" && first != "." && first != ".." && !strings.HasPrefix(arg, "~")
+}
+
+// searchBases resolves arg against each directory of Options.SearchPath in order, returning the first
+// that names a directory.
+func (rs *resolver) searchBases(arg string) (Resolution, bool) {
+	for _, base := range rs.opts.SearchPath {
+		sub := *rs
+		sub.cwd = base
+		p, err := sub.resolveLinuxLike(arg)
+		if err != nil {
+			continue
+		}
+		if d, err := rs.verifyDir(p); err == nil {
+			rs.tracef(1, "not under the cwd; found under search path entry %s", base)
+			return Resolution{Resolved: d}, true
+		}
+		rs.tracef(2, "  not under search path entry %s", base)
+	}
+	return Resolution{}, false
+}
+
+// resolveLinuxLike resolves ~ and ~user, relative, and cleans the path.
+func (rs *resolver) resolveLinuxLike(arg string) (string, error) {
+	p, err := rs.expandVars(arg, '$')
+	if err != nil {
+		return "", err
+	}
+	// ~ or ~/...
+	if p == "~" {
+		if p, err = rs.homeDir(); err != nil {
+			return "", err
+		}
+	} else if strings.HasPrefix(p, "~/") {
+		home, err := rs.homeDir()
+		if err != nil {
+			return "", err
+		}
+		p = home + "/" + p[2:]
+	} else if strings.HasPrefix(p, "~") {
+		// ~user or ~user/...
+		name, rest, _ := strings.Cut(p[1:], "/")
+		u, err := user.Lookup(name)
+		var unknown user.UnknownUserError
+		if errors.As(err, &unknown) {
+			return "", fmt.Errorf("%w: no such user: %s", ErrNotFound, name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot look up user %s: %w", name, err)
+		}
+		p = u.HomeDir + "/" + rest
+	} else if !strings.HasPrefix(p, "/") {
+		// relative
+		p = rs.cwd + "/" + p
+	}
+	if rs.opts.Physical {
+		return physicalPath(p)
+	}
+	return filepath.Clean(p), nil
+}
+
+// physicalPath resolves the absolute path p component by component, evaluating symlinks before
+// applying "..", like cd -P. Every component must exist.
+func physicalPath(p string) (string, error) {
+	cur := "/"
+	for _, c := range strings.Split(p, "/") {
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			cur = filepath.Dir(cur)
+			continue
+		}
+		next, err := filepath.EvalSymlinks(filepath.Join(cur, c))
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, filepath.Join(cur, c))
+		}
+		if errors.Is(err, syscall.ENAMETOOLONG) {
+			return "", longPathError(filepath.Join(cur, c))
+		}
+		if err != nil {
+			return "", err
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// checkAccess warns when the resolved directory p exists but cannot be both read and entered, which
+// would only make the cd after it fail; with Options.StrictPerms it is an error instead.
+func (rs *resolver) checkAccess(p string) error {
+	const rOK, xOK = 4, 1
+	err := syscall.Access(p, rOK|xOK)
+	if err == nil {
+		return nil
+	}
+	if rs.opts.StrictPerms {
+		return fmt.Errorf("%s resolved but is not accessible: %w", p, err)
+	}
+	rs.warnf("%s resolved but may not be accessible: %v", p, err)
+	return nil
+}
+
+// checkNoSymlinks rejects a path whose intermediate components are symlinks, for Options.NoFollowSymlinks.
+// The final component may be a symlink; it is returned as-is.
+func checkNoSymlinks(p string) error {
+	dir := filepath.Dir(p)
+	for d := dir; d != "/" && d != "."; d = filepath.Dir(d) {
+		if info, err := os.Lstat(d); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink (symlinks are not followed)", d)
+		}
+	}
+	return nil
+}
+
+// expandVars expands environment variable references using Options.LookupEnv. With style '$' it expands $VAR and ${VAR};
+// with style '%' it expands Windows-style %VAR%. Unset variables are an error rather than expanding to empty.
+func (rs *resolver) expandVars(s string, style byte) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != style {
+			b.WriteByte(s[i])
+			continue
+		}
+		name, n := varRef(s[i:], style)
+		if n == 0 {
+			b.WriteByte(s[i])
+			continue
+		}
+		v, ok := rs.opts.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		b.WriteString(v)
+		i += n - 1
+	}
+	return b.String(), nil
+}
+
+// varRef parses a variable reference at the start of s, returning its name and byte length (0 if none).
+func varRef(s string, style byte) (string, int) {
+	if style == '%' {
+		j := strings.IndexByte(s[1:], '%')
+		if j <= 0 || !isVarName(s[1:1+j], "()") {
+			return "", 0
+		}
+		return s[1 : 1+j], j + 2
+	}
+	if strings.HasPrefix(s, "${") {
+		j := strings.IndexByte(s, '}')
+		if j < 0 || !isVarName(s[2:j], "") {
+			return "", 0
+		}
+		return s[2:j], j + 1
+	}
+	j := 1
+	for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+		j++
+	}
+	if !isVarName(s[1:j], "") {
+		return "", 0
+	}
+	return s[1:j], j
+}
+
+// isVarName reports whether name is an identifier, additionally allowing the characters in extra after the first.
+func isVarName(name, extra string) bool {
+	for i, r := range name {
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && (unicode.IsDigit(r) || strings.ContainsRune(extra, r))) {
+			continue
+		}
+		return false
+	}
+	return name != ""
+}
+
+// notFoundError reports a path that does not exist. It wraps both ErrNotFound and the underlying
+// error, so errors.Is matches fs.ErrNotExist too.
+type notFoundError struct {
+	path string
+	err  error
+}
+
+func (e notFoundError) Error() string   { return ErrNotFound.Error() + ": " + e.path }
+func (e notFoundError) Unwrap() []error { return []error{ErrNotFound, e.err} }
+
+// verifyDir returns p if it is an existing directory, or with Options.Parent the directory containing p if it is a file.
+func (rs *resolver) verifyDir(p string) (string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		if rs.reparseDir(p, err) {
+			return p, nil
+		}
+		if li, lerr := os.Lstat(p); lerr == nil && li.Mode()&fs.ModeSymlink != 0 {
+			return "", brokenSymlinkError(p)
+		}
+		if errors.Is(err, syscall.ENAMETOOLONG) {
+			return "", longPathError(p)
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", notFoundError{p, err}
+		}
+		return "", err
+	}
+	if rs.opts.Parent && info.Mode().IsRegular() {
+		return filepath.Dir(p), nil
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%w: %s", ErrNotDirectory, p)
+	}
+	return p, nil
+}
@@ -0,0 +1,63 @@
+package wslpath
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestTildeUser(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	for in, want := range map[string]string{
+		"~" + u.Username:             u.HomeDir,
+		"~" + u.Username + "/a/../b": u.HomeDir + "/b",
+	} {
+		if got, err := ResolveLinuxLike(in, "/", "/home/other", Options{}); err != nil || got != want {
+			t.Errorf("ResolveLinuxLike(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ResolveLinuxLike("~no-such-user-wslcd/x", "/", "/", Options{}); err == nil || !strings.Contains(err.Error(), "no such user: no-such-user-wslcd") {
+		t.Errorf("ResolveLinuxLike(~no-such-user-wslcd/x) = %v; want a no such user error", err)
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	dir := t.TempDir()
+	long := dir + strings.Repeat("/"+strings.Repeat("d", 100), 50) // over PATH_MAX
+	for _, in := range []string{long, dir + "/" + strings.Repeat("n", 300)} {
+		_, err := ResolveTarget(in, "/", "/", Options{})
+		if !errors.Is(err, syscall.ENAMETOOLONG) {
+			t.Fatalf("ResolveTarget(%d bytes) = %v; want ENAMETOOLONG", len(in), err)
+		}
+		msg := err.Error()
+		if want := fmt.Sprintf("path too long (%d bytes) at %s...", len(in), in[:16]); !strings.HasPrefix(msg, want) {
+			t.Errorf("ResolveTarget(%d bytes) error %q; want it to start %q", len(in), msg, want)
+		}
+		if !strings.Contains(msg, `\\?\`) || !strings.Contains(msg, "wslcd --bookmark") {
+			t.Errorf("ResolveTarget(%d bytes) error %q; want hints on the long-path prefix and bookmarks", len(in), msg)
+		}
+	}
+}
+
+func TestEmptyHomeUsesUserDatabase(t *testing.T) {
+	u, err := user.Current()
+	if err != nil || u.HomeDir == "" {
+		t.Skip("no user database entry for the current user")
+	}
+	for in, want := range map[string]string{"~": u.HomeDir, "~/a/b": filepath.Join(u.HomeDir, "a/b")} {
+		if got, err := ResolveLinuxLike(in, "/", "", Options{}); err != nil || got != want {
+			t.Errorf("ResolveLinuxLike(%q) with no HOME = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	// HOME still wins when set.
+	if got, err := ResolveLinuxLike("~/a", "/", "/elsewhere", Options{}); err != nil || got != "/elsewhere/a" {
+		t.Errorf("ResolveLinuxLike(~/a) = %q, %v; want /elsewhere/a", got, err)
+	}
+}
@@ -0,0 +1,455 @@
+package wslpath
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// collapsedMatch is an entry whose name is a case-insensitive prefix of a collapsed tail.
+type collapsedMatch struct { name string; plen int; score int }
+
+// collapsedMatches lists the directories in curr whose names are case-insensitive prefixes of tail,
+// longest first, then by CaseScore, then by name. more is set if further path follows tail.
+func (rs *resolver) collapsedMatches(curr, tail string, more bool) ([]collapsedMatch, error) {
+	ents, err := rs.fs.readDir(curr)
+	if errors.Is(err, syscall.ENAMETOOLONG) { return nil, longPathError(curr) }
+	if err != nil { return nil, fmt.Errorf("cannot read directory %s: %v", curr, err) }
+
+	var ms []collapsedMatch
+	var broken string
+	for _, e := range ents {
+		n := e.Name()
+		ln := rs.namePrefix(tail, n)
+		if ln < 0 || rs.ignored(n) { continue }
+		final := ln == len(tail) && !more
+		if !final && !rs.canDescend(e) { continue }
+		full := filepath.Join(curr, n)
+		isDir, err := rs.isDirFollowSymlink(full, e)
+		if errors.Is(err, errBrokenSymlink) { broken = full }
+		// With Options.Parent a file may complete the tail; verifyDir then maps it to its directory.
+		if err != nil || (!isDir && !(rs.opts.Parent && final)) { continue }
+		ms = append(ms, collapsedMatch{name: n, plen: ln, score: CaseScore(tail[:ln], n)})
+	}
+
+	sort.SliceStable(ms, func(i, j int) bool {
+		if ms[i].plen != ms[j].plen { return ms[i].plen > ms[j].plen }
+		if ms[i].score != ms[j].score { return ms[i].score > ms[j].score }
+		return ms[i].name < ms[j].name
+	})
+	if len(ms) == 0 && broken != "" { return nil, brokenSymlinkError(broken) }
+	return ms, nil
+}
+
+// sameName reports whether a directory name matches an input segment: ignoring case (see foldRune),
+// or exactly with Options.CaseSensitive.
+func (rs *resolver) sameName(input, name string) bool {
+	if rs.opts.CaseSensitive {
+		return input == name
+	}
+	return foldEqual(input, name)
+}
+
+// sameTrimmedName is sameName ignoring trailing spaces and dots, which Windows tools can leave on names
+// that Explorer shows trimmed.
+func (rs *resolver) sameTrimmedName(input, name string) bool {
+	return rs.sameName(strings.TrimRight(input, " ."), strings.TrimRight(name, " ."))
+}
+
+// namePrefix returns the length in bytes of the prefix of s that sameName matches to name, or -1.
+func (rs *resolver) namePrefix(s, name string) int {
+	if rs.opts.CaseSensitive {
+		if strings.HasPrefix(s, name) {
+			return len(name)
+		}
+		return -1
+	}
+	return foldPrefix(s, name)
+}
+
+// ignored reports whether a directory name matches one of the Options.Ignore patterns, ignoring case.
+func (rs *resolver) ignored(name string) bool {
+	for _, pat := range rs.opts.Ignore {
+		if ok, _ := filepath.Match(foldString(pat), foldString(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// strictNote qualifies "match" in error messages when Options.CaseSensitive is set.
+func (rs *resolver) strictNote() string {
+	if rs.opts.CaseSensitive {
+		return "exact-case "
+	}
+	return ""
+}
+
+func argHead(s string) string {
+	if len(s) == 0 { return "" }
+	if len(s) > 16 { return s[:16] + "..." }
+	return s
+}
+
+func (rs *resolver) pickCaseInsensitiveEntry(dir, want string) (string, error) {
+	ents, err := rs.fs.readDir(dir)
+	if err != nil { return "", err }
+	wantLower := strings.ToLower(want)
+	type pair struct { name string; score int }
+	var matches []pair
+	for _, e := range ents {
+		n := e.Name()
+		if rs.sameName(want, n) {
+			matches = append(matches, pair{name: n, score: CaseScore(want, n)})
+		}
+	}
+	if len(matches) == 0 {
+		candidate := filepath.Join(dir, wantLower)
+		if st, err := rs.fs.stat(candidate); err == nil && st.IsDir() && rs.sameName(want, wantLower) {
+			rs.tracef(1, "mapped %q to %s", want, candidate)
+			return wantLower, nil
+		}
+		return "", fmt.Errorf("no %smatch for %s in %s", rs.strictNote(), want, dir)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score { return matches[i].score > matches[j].score }
+		return matches[i].name < matches[j].name
+	})
+	for _, m := range matches[1:] { rs.tracef(2, "  also matched %s (score=%d)", filepath.Join(dir, m.name), m.score) }
+	rs.tracef(1, "mapped %q to %s", want, filepath.Join(dir, matches[0].name))
+	return matches[0].name, nil
+}
+
+// candidate is a fully matched path. score is the total CaseScore and segScores the CaseScore of each segment.
+// fuzz is the total edit distance of segments matched with Options.Fuzzy; exact matches have 0.
+// depth is the number of segments matched, which is less than all of them only for the nearest ancestor.
+type candidate struct { fullPath string; score int; segScores []int; fuzz int; depth int }
+
+// compareSegScores orders per-segment case scores deepest segment first, so a better case match further
+// down the path wins over any number of better matches above it. It returns >0 if a is the better match.
+func compareSegScores(a, b []int) int {
+	for i := min(len(a), len(b)) - 1; i >= 0; i-- {
+		if a[i] != b[i] { return a[i] - b[i] }
+	}
+	return 0
+}
+
+// exploreCandidates walks segs beneath root depth-first and returns every fully matched path, and the
+// best scoring of the deepest directories reached along the way for Options.Nearest.
+// At most Options.MaxCandidates branches are explored before the walk turns greedy.
+// Directories are tracked by real path, so one reached again at the same level through another symlink is
+// not walked twice. A symlink loop cannot recurse forever, since the walk never goes deeper than segs.
+// With Options.FirstMatch the walk stops at the first full match, found by following the best match first.
+// Once a full match is found, a branch whose fuzz and case scores so far already rank it below that match
+// whatever its remaining segments match is not explored further (branch and bound), unless a segment is a
+// glob, which must match a single directory, or Options.Explain wants the runners-up too.
+// With Options.BreadthFirst the levels are walked one at a time instead, stopping once a path matching every
+// segment in exact case is found, since nothing can outrank it; the candidates found are otherwise the same.
+func (rs *resolver) exploreCandidates(root string, segs []string) ([]candidate, candidate, error) {
+	type state struct { dir string; real string; idx int; score int; segScores []int; fuzz int }
+	var results []candidate
+	deepest := candidate{fullPath: root}
+	var broken, tooLong string
+	maxBranches, explored, capped := rs.opts.MaxCandidates, 0, false
+	if maxBranches <= 0 { maxBranches = DefaultMaxCandidates }
+	visited := map[string]bool{} // real path + level
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil { realRoot = root }
+	// best indexes the best full match in results so far, and maxScores holds the best CaseScore each
+	// segment can reach, for pruning.
+	prune := rs.opts.Explain <= 0 && !slices.ContainsFunc(segs, hasGlobMeta)
+	best := -1
+	maxScores := make([]int, len(segs))
+	for i, seg := range segs { maxScores[i] = CaseScore(seg, seg) }
+	pruned := 0
+	keep := func(c candidate) {
+		results = append(results, c)
+		if best < 0 || c.fuzz < results[best].fuzz || (c.fuzz == results[best].fuzz && compareSegScores(c.segScores, results[best].segScores) > 0) { best = len(results) - 1 }
+	}
+	// step explores the directory of st, handing each match on to descend: depth-first, the walk follows
+	// it at once, while breadth-first it is queued behind the rest of the level. Full matches are checked
+	// at once either way.
+	var step, descend func(st state) error
+	var queue []state
+	descend = func(st state) error {
+		if rs.opts.BreadthFirst && st.idx < len(segs) {
+			queue = append(queue, st)
+			return nil
+		}
+		return step(st)
+	}
+	step = func(st state) error {
+		// Out of time, or done with a first match: keep what was found, explore nothing more.
+		if rs.ctx.Err() != nil || (rs.opts.FirstMatch && len(results) > 0) { return nil }
+		if st.idx > rs.maxDepth() { return tooDeepError(st.dir, rs.maxDepth()) }
+		if prune && st.idx < len(segs) && best >= 0 && !canTie(st.segScores, st.fuzz, results[best], maxScores) {
+			rs.tracef(2, "  pruned %s: cannot reach the score of %s", st.dir, results[best].fullPath)
+			pruned++
+			return nil
+		}
+		key := st.real + "\x00" + strconv.Itoa(st.idx)
+		if visited[key] {
+			rs.tracef(2, "  %s is %s, already explored", st.dir, st.real)
+			return nil
+		}
+		visited[key] = true
+		if st.idx >= len(segs) {
+			info, err := rs.fs.stat(st.dir)
+			if errors.Is(err, syscall.ENAMETOOLONG) { tooLong = st.dir }
+			if err != nil && rs.reparseDir(st.dir, err) { keep(candidate{fullPath: st.dir, score: st.score, segScores: st.segScores, fuzz: st.fuzz}) }
+			if err != nil { return nil }
+			if info.IsDir() || (rs.opts.Parent && info.Mode().IsRegular()) { keep(candidate{fullPath: st.dir, score: st.score, segScores: st.segScores, fuzz: st.fuzz}) }
+			return nil
+		}
+		if st.idx > deepest.depth || (st.idx == deepest.depth && st.idx > 0 && compareSegScores(st.segScores, deepest.segScores) > 0) {
+			deepest = candidate{fullPath: st.dir, score: st.score, segScores: st.segScores, fuzz: st.fuzz, depth: st.idx}
+		}
+		seg := segs[st.idx]
+		ents, err := rs.fs.readDir(st.dir)
+		if errors.Is(err, syscall.ENAMETOOLONG) { tooLong = st.dir }
+		if err != nil { return nil }
+		type match struct { name string; score int; path string; dist int; link bool }
+		var ms []match
+		collect := func(same func(seg, n string) bool) {
+			for _, e := range ents {
+				n := e.Name()
+				if !same(seg, n) && !(hasGlobMeta(seg) && rs.globMatch(seg, n)) { continue }
+				if rs.ignored(n) { continue }
+				full := filepath.Join(st.dir, n)
+				last := st.idx == len(segs)-1
+				if !last && !rs.canDescend(e) { continue }
+				isDir, err := rs.isDirFollowSymlink(full, e)
+				if errors.Is(err, errBrokenSymlink) { broken = full }
+				if errors.Is(err, syscall.ENAMETOOLONG) { tooLong = full }
+				if err == nil && !isDir && rs.opts.ThroughArchives {
+					if mnt, ok := rs.archiveMount(full); ok { full, isDir = mnt, true }
+				}
+				if err != nil || (!isDir && !(rs.opts.Parent && last)) { continue }
+				ms = append(ms, match{name: n, score: CaseScore(seg, n), path: full, link: e.Type()&fs.ModeSymlink != 0})
+				rs.tracef(2, "  level %d: %q matches %s (score=%d)", st.idx, seg, full, CaseScore(seg, n))
+			}
+		}
+		collect(rs.sameName)
+		if len(ms) == 0 && rs.opts.TrimTrailing {
+			collect(rs.sameTrimmedName)
+		}
+		if len(ms) == 0 && rs.opts.ExpandShortNames && isShortName(seg) {
+			if paths := rs.shortNameMatches(st.dir, seg, ents, st.idx == len(segs)-1); len(paths) > 0 {
+				p, err := rs.pickOne("short name "+seg, paths)
+				if err != nil { return err }
+				rs.tracef(1, "short name %s expanded to %s", seg, p)
+				ms = append(ms, match{name: filepath.Base(p), path: p})
+			}
+		}
+		if len(ms) == 0 && rs.opts.Fuzzy {
+			for _, f := range rs.fuzzyMatches(st.dir, seg, ents, st.idx == len(segs)-1) {
+				rs.tracef(2, "  level %d: %q fuzzily matches %s (dist=%d, score=%d)", st.idx, seg, f.name, f.dist, f.score)
+				ms = append(ms, match{name: f.name, score: f.score, path: filepath.Join(st.dir, f.name), dist: f.dist, link: f.link})
+			}
+		}
+		if len(ms) == 0 { return nil }
+		sort.SliceStable(ms, func(i, j int) bool {
+			if ms[i].dist != ms[j].dist { return ms[i].dist < ms[j].dist }
+			if ms[i].score != ms[j].score { return ms[i].score > ms[j].score }
+			return ms[i].name < ms[j].name
+		})
+		// An exact-case match of the last segment outranks its siblings, so when it is a result they are not explored,
+		// unless Options.Explain wants them ranked. Above the last segment a sibling may still lead to a better match.
+		next := func(m match) (state, bool) {
+			real := filepath.Join(st.real, m.name)
+			if m.link {
+				r, err := filepath.EvalSymlinks(m.path)
+				if err != nil { return state{}, false }
+				real = r
+			}
+			return state{dir: m.path, real: real, idx: st.idx + 1, score: st.score + m.score, segScores: append(slices.Clip(st.segScores), m.score), fuzz: st.fuzz + m.dist}, true
+		}
+		if ms[0].name == seg && st.idx == len(segs)-1 && rs.opts.Explain <= 0 {
+			n := len(results)
+			if nst, ok := next(ms[0]); ok {
+				if err := descend(nst); err != nil { return err }
+			}
+			if len(results) > n { return nil }
+			ms = ms[1:]
+		}
+		if rs.opts.Parallel > 1 && len(ms) > 1 && !capped && !rs.opts.FirstMatch {
+			var dirs, files []string
+			for _, m := range ms {
+				if st.idx == len(segs)-1 { files = append(files, m.path) } else { dirs = append(dirs, m.path) }
+			}
+			rs.fs.prefetch(dirs, files, rs.opts.Parallel)
+		}
+		for _, m := range ms {
+			// Past the branch cap only the best-scored branch at each level is followed.
+			if explored >= maxBranches {
+				if !capped { rs.tracef(1, "explored %d branches; continuing greedily", explored) }
+				capped = true
+				ms = ms[:1]
+			}
+			explored++
+			if nst, ok := next(m); ok {
+				if err := descend(nst); err != nil { return err }
+			}
+			if capped { break }
+		}
+		return nil
+	}
+	if len(segs) == 0 {
+		if info, err := rs.fs.stat(root); err == nil && info.IsDir() { results = append(results, candidate{fullPath: root, score: 0}) }
+		return results, deepest, nil
+	}
+	if err := step(state{dir: root, real: realRoot, idx: 0, score: 0}); err != nil { return nil, deepest, err }
+	for len(queue) > 0 {
+		if i := slices.IndexFunc(results, exactCandidate); i >= 0 {
+			rs.tracef(1, "%s matches every segment in exact case; not exploring the %d remaining branches", results[i].fullPath, len(queue))
+			break
+		}
+		st := queue[0]
+		queue = queue[1:]
+		if err := step(st); err != nil { return nil, deepest, err }
+	}
+	if pruned > 0 { rs.tracef(1, "pruned %d branches that could not match as well as %s", pruned, results[best].fullPath) }
+	if err := rs.ctx.Err(); err != nil {
+		if len(results) == 0 && !rs.opts.Nearest { return nil, deepest, fmt.Errorf("gave up walking %s: %w", root, err) }
+		rs.tracef(1, "gave up walking %s (%v); using the %d candidates found so far", root, err, len(results))
+	}
+	if len(results) == 0 && tooLong != "" && !rs.opts.Nearest { return nil, deepest, longPathError(tooLong) }
+	if len(results) == 0 && broken != "" && !rs.opts.Nearest { return nil, deepest, brokenSymlinkError(broken) }
+	return results, deepest, nil
+}
+
+// canTie reports whether a branch that matched the leading segments with case scores partial and total
+// edit distance fuzz can still end up ranked at least level with best, given the highest score maxScores
+// allows each segment. Fuzz only grows down a branch; among equal fuzz the deepest segments compare first,
+// so only if best already has the highest score at every remaining segment do the scores so far decide.
+func canTie(partial []int, fuzz int, best candidate, maxScores []int) bool {
+	if fuzz != best.fuzz {
+		return fuzz < best.fuzz
+	}
+	for i := len(partial); i < len(maxScores); i++ {
+		if best.segScores[i] < maxScores[i] {
+			return true
+		}
+	}
+	return compareSegScores(partial, best.segScores[:len(partial)]) >= 0
+}
+
+// exactCandidate reports whether c matched every segment exactly as typed.
+func exactCandidate(c candidate) bool {
+	return c.fuzz == 0 && !slices.ContainsFunc(c.segScores, func(s int) bool { return s < caseScoreExact })
+}
+
+// errBrokenSymlink is returned by resolver.isDirFollowSymlink for a symlink whose target does not exist.
+var errBrokenSymlink = errors.New("broken symlink")
+
+func (rs *resolver) isDirFollowSymlink(full string, de fs.DirEntry) (bool, error) {
+	if de.IsDir() { return true, nil }
+	info, err := rs.fs.stat(full)
+	if err != nil {
+		if de.Type()&fs.ModeSymlink != 0 && errors.Is(err, fs.ErrNotExist) { return false, errBrokenSymlink }
+		if rs.reparseDir(full, err) { return true, nil }
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// reparseDir reports whether p, which stat failed on with err, is taken for a directory under
+// Options.ReparseAsDir: stat fails with an I/O error on reparse points DrvFs cannot follow, such as
+// OneDrive placeholders, while Lstat still shows a directory or the link DrvFs surfaces it as.
+func (rs *resolver) reparseDir(p string, err error) bool {
+	if !rs.opts.ReparseAsDir || !errors.Is(err, syscall.EIO) {
+		return false
+	}
+	info, lerr := os.Lstat(p)
+	if lerr != nil || (!info.IsDir() && info.Mode()&fs.ModeSymlink == 0) {
+		return false
+	}
+	rs.tracef(1, "%s: %v; treating the reparse point as a directory", p, err)
+	return true
+}
+
+// canDescend reports whether the walkers may continue below de. With Options.NoFollowSymlinks a symlink
+// may only be the final path component.
+func (rs *resolver) canDescend(de fs.DirEntry) bool {
+	return !rs.opts.NoFollowSymlinks || de.Type()&fs.ModeSymlink == 0
+}
+
+// maxDepth returns how many directories a walk may descend, Options.MaxDepth or DefaultMaxDepth.
+func (rs *resolver) maxDepth() int {
+	if rs.opts.MaxDepth > 0 {
+		return rs.opts.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// tooDeepError reports a walk stopped at p for descending more than max directories.
+func tooDeepError(p string, max int) error {
+	return fmt.Errorf("%w: gave up at %s, more than %d directories deep\nHint: set WSLCD_MAX_DEPTH to allow deeper paths", ErrTooDeep, argHead(p), max)
+}
+
+// brokenSymlinkError describes a dangling symlink at p, naming its target when readable.
+func brokenSymlinkError(p string) error {
+	if target, err := os.Readlink(p); err == nil {
+		return fmt.Errorf("%w: broken symlink: %s -> %s", ErrNotFound, p, target)
+	}
+	return fmt.Errorf("%w: broken symlink: %s", ErrNotFound, p)
+}
+
+// longPathError explains a path that Linux rejected with ENAMETOOLONG: a name over 255 bytes
+// or a path over PATH_MAX, which deeply nested Windows directories can reach.
+func longPathError(p string) error {
+	return fmt.Errorf("path too long (%d bytes) at %s: %w\nHint: Windows reaches such paths with the \\\\?\\ long-path prefix, but Linux cannot; bookmark a shorter parent and cd from there (wslcd --bookmark <name> <dir>)", len(p), argHead(p), syscall.ENAMETOOLONG)
+}
+
+// Weights of the parts of a CaseScore. Names are at most 255 characters, so each part stays below the
+// weight of the one above it and the decimal digits of a score read as exact, run and positional.
+const (
+	caseScoreExact = 1000000
+	caseScoreRun   = 1000
+)
+
+// CaseScore grades how closely the case of candidate follows input; higher means a closer match. It encodes,
+// in order of weight: whether the two are identical, the longest run of positions where they agree
+// exactly, and the number of such positions less the difference in length. So against input "Readme",
+// "Readme" scores 1006006, "ReadMe" 4005 and "README" 1001, and comparing scores as integers ranks
+// candidates by all three.
+func CaseScore(input, candidate string) int {
+	inRunes := []rune(input)
+	cRunes := []rune(candidate)
+	n := min(len(inRunes), len(cRunes))
+	positional, run, longest := 0, 0, 0
+	for i := 0; i < n; i++ {
+		if inRunes[i] != cRunes[i] {
+			run = 0
+			continue
+		}
+		positional++
+		run++
+		longest = max(longest, run)
+	}
+	score := longest*caseScoreRun + max(positional-abs(len(inRunes)-len(cRunes)), 0)
+	if input == candidate {
+		score += caseScoreExact
+	}
+	return score
+}
+
+// SplitCaseScore takes a CaseScore apart: whether the names were identical, the longest exact-case run,
+// and the exact-case positions less the difference in length.
+func SplitCaseScore(score int) (exact bool, run, positional int) {
+	return score >= caseScoreExact, score % caseScoreExact / caseScoreRun, score % caseScoreRun
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,216 @@
+package wslpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDeepestExactCaseWins(t *testing.T) {
+	tests := []struct {
+		dirs []string
+		in   string
+		want string
+	}{
+		// The better case above the last segment doesn't outweigh an exact last segment.
+		{[]string{"Users/Me/DOCS", "users/me/docs"}, `C:\Users\Me\docs`, "users/me/docs"},
+		{[]string{"Users/Me", "Users/me"}, `C:\USERS\me`, "Users/me"},
+		{[]string{"A/b/C", "a/B/c"}, `C:\a\b\C`, "A/b/C"},
+		// With the last segments alike, the one above decides.
+		{[]string{"X/Proj/src", "x/proj/src"}, `C:\X\proj\src`, "x/proj/src"},
+		{[]string{"Data/LOGS/Today", "data/Logs/today", "DATA/logs/Today"}, `C:\data\logs\Today`, "DATA/logs/Today"},
+	}
+	for _, tt := range tests {
+		mnt := t.TempDir()
+		for _, d := range tt.dirs {
+			mkdirs(t, mnt, "c/"+d)
+		}
+		got, err := ResolveTarget(tt.in, "/", "/", Options{MountRoot: mnt})
+		if want := filepath.Join(mnt, "c", tt.want); err != nil || got != want {
+			t.Errorf("ResolveTarget(%q) among %v = %q, %v; want %q", tt.in, tt.dirs, got, err, want)
+		}
+	}
+}
+
+func TestCaseScore(t *testing.T) {
+	tests := []struct {
+		input, candidate string
+		want             int
+	}{
+		{"Readme", "Readme", 1006006},
+		{"Readme", "readme", 5005},
+		{"Readme", "ReadMe", 4005},
+		{"Readme", "README", 1001},
+		{"Readme", "rEADME", 0},
+		{"Readme", "Readme2", 6005}, // one longer: the run is whole, a position is lost
+		{"Readme", "Read", 4002},
+		{"Café", "CAFÉ", 1001}, // by rune, not byte
+		{"", "", 1000000},
+	}
+	for _, tt := range tests {
+		if got := CaseScore(tt.input, tt.candidate); got != tt.want {
+			t.Errorf("CaseScore(%q, %q) = %d; want %d", tt.input, tt.candidate, got, tt.want)
+		}
+	}
+	if exact, run, pos := SplitCaseScore(1006006); !exact || run != 6 || pos != 6 {
+		t.Errorf("SplitCaseScore(1006006) = %v, %d, %d; want true, 6, 6", exact, run, pos)
+	}
+	if exact, run, pos := SplitCaseScore(4005); exact || run != 4 || pos != 5 {
+		t.Errorf("SplitCaseScore(4005) = %v, %d, %d; want false, 4, 5", exact, run, pos)
+	}
+}
+
+func TestCandidateOrder(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/README", "c/ReadMe", "c/readme", "c/rEADME", "c/Docs/Readme", "c/docs/README")
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		// A contiguous run of matching case beats scattered matching positions.
+		{`C:\Readme`, []string{"readme", "ReadMe", "README", "rEADME"}},
+		// The exact last segment wins over a better case above it.
+		{`C:\docs\Readme`, []string{"Docs/Readme", "docs/README"}},
+	}
+	for _, tt := range tests {
+		r, err := ResolveDetailed(tt.in, "/", "/", Options{MountRoot: mnt, Explain: 10})
+		if err != nil {
+			t.Fatalf("ResolveDetailed(%q): %v", tt.in, err)
+		}
+		var got []string
+		for _, rk := range r.Ranked {
+			rel, _ := filepath.Rel(filepath.Join(mnt, "c"), rk.Path)
+			got = append(got, rel)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ResolveDetailed(%q) ranked %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	const depth = 60
+	mnt := t.TempDir()
+	mkdirs(t, mnt, "c"+strings.Repeat("/Deep", depth))
+	in := "C:" + strings.Repeat(`\deep`, depth)
+	for _, max := range []int{0, depth} {
+		got, err := ResolveTarget(in, "/", "/", Options{MountRoot: mnt, MaxDepth: max})
+		if want := filepath.Join(mnt, "c"+strings.Repeat("/Deep", depth)); err != nil || got != want {
+			t.Errorf("ResolveTarget(%d segments) with MaxDepth %d = %q, %v; want %q", depth, max, got, err, want)
+		}
+	}
+	for _, in := range []string{in, "C:" + strings.Repeat("deep", depth)} {
+		_, err := ResolveTarget(in, "/", "/", Options{MountRoot: mnt, MaxDepth: depth - 1})
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("more than %d directories deep", depth-1)) || !strings.Contains(err.Error(), "WSLCD_MAX_DEPTH") {
+			t.Errorf("ResolveTarget(%.12s...) with MaxDepth %d = %v; want a too deep error", in, depth-1, err)
+		}
+	}
+}
+
+// caseTree creates a tree depth levels deep beneath root in which every directory holds one of each of names.
+func caseTree(tb testing.TB, root string, depth int, names ...string) {
+	tb.Helper()
+	if depth == 0 {
+		return
+	}
+	for _, n := range names {
+		mkdirs(tb, root, n)
+		caseTree(tb, filepath.Join(root, n), depth-1, names...)
+	}
+}
+
+// benchmarkExplore walks a tree of case variants in which every branch matches to the last level, with a
+// fresh resolver (and so a cold cache) each time.
+func benchmarkExplore(b *testing.B, parallel int) {
+	root := b.TempDir()
+	caseTree(b, root, 5, "dir", "Dir", "DIR", "dIR")
+	segs := []string{"dIr", "dIr", "dIr", "dIr", "dIr"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rs := newResolver(context.Background(), "/", "/", Options{Parallel: parallel, MaxCandidates: 1 << 20})
+		cands, _, err := rs.exploreCandidates(root, segs)
+		if err != nil || len(cands) == 0 {
+			b.Fatalf("exploreCandidates = %d candidates, %v", len(cands), err)
+		}
+	}
+}
+
+// On a local filesystem the parallel walk gains nothing over the serial one, hence the serial default;
+// listing concurrently pays off only where each ReadDir waits on a slow DrvFs or network mount.
+func BenchmarkExploreCandidatesSerial(b *testing.B)   { benchmarkExplore(b, 1) }
+func BenchmarkExploreCandidatesParallel(b *testing.B) { benchmarkExplore(b, 8) }
+
+func TestPruningKeepsResults(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/program/src", "c/Projects/src", "c/PROJECTS/Src")
+	// Every match of a glob counts towards its ambiguity, however it scores.
+	if got, err := ResolveTarget(`C:\pro*\src`, "/", "/", Options{MountRoot: mnt}); !errors.Is(err, ErrAmbiguous) {
+		t.Errorf("ResolveTarget(C:\\pro*\\src) = %q, %v; want ErrAmbiguous", got, err)
+	}
+	// Explaining the choice shows the runners-up, which pruning would skip.
+	r, err := ResolveDetailed(`C:\projects\src`, "/", "/", Options{MountRoot: mnt, Explain: 5})
+	if err != nil || len(r.Ranked) != 2 || r.Considered != 2 {
+		t.Errorf("ResolveDetailed(C:\\projects\\src) with Explain = %d ranked, %d considered, %v; want 2 and 2", len(r.Ranked), r.Considered, err)
+	}
+
+	root := t.TempDir()
+	caseTree(t, root, 3, "proj", "Proj", "PROJ", "pRoJ")
+	for _, segs := range [][]string{{"proj", "proj", "proj"}, {"Proj", "pRoj", "PROJ"}, {"pROJ", "PRoj", "prOJ"}} {
+		pruned, all := newResolver(context.Background(), "/", "/", Options{}), newResolver(context.Background(), "/", "/", Options{Explain: 1})
+		pc, _, err := pruned.exploreCandidates(root, segs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ac, _, err := all.exploreCandidates(root, segs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pruned.sortCandidates(pc)
+		all.sortCandidates(ac)
+		if pc[0].fullPath != ac[0].fullPath || compareSegScores(pc[0].segScores, ac[0].segScores) != 0 {
+			t.Errorf("segments %q: best %s with pruning, %s without", segs, pc[0].fullPath, ac[0].fullPath)
+		}
+		if len(pruned.fs.lists) > len(all.fs.lists) {
+			t.Errorf("segments %q: pruning listed %d directories, more than the %d without", segs, len(pruned.fs.lists), len(all.fs.lists))
+		}
+	}
+}
+
+// BenchmarkPruning walks a wide tree of case variants with and without pruning, which Options.Explain turns
+// off, and reports how many directories each lists.
+func BenchmarkPruning(b *testing.B) {
+	root := b.TempDir()
+	caseTree(b, root, 4, "work", "Work", "WORK", "wOrK", "WoRk", "woRK")
+	segs := []string{"Work", "work", "WORK", "work"}
+	for _, bc := range []struct {
+		name string
+		opts Options
+	}{{"pruned", Options{}}, {"unpruned", Options{Explain: 1}}} {
+		b.Run(bc.name, func(b *testing.B) {
+			lists := 0
+			for i := 0; i < b.N; i++ {
+				rs := newResolver(context.Background(), "/", "/", bc.opts)
+				if _, _, err := rs.exploreCandidates(root, segs); err != nil {
+					b.Fatal(err)
+				}
+				lists = len(rs.fs.lists)
+			}
+			b.ReportMetric(float64(lists), "readdirs/op")
+		})
+	}
+}
+
+func TestExplainExactInput(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Users/me/Docs", "c/Users/ME/docs", "c/Users/me/DOCS")
+	for _, in := range []string{`C:\Users\me\Docs`, `..\me\Docs`} {
+		r, err := ResolveDetailed(in, filepath.Join(mnt, "c/Users/me"), "/", Options{MountRoot: mnt, Explain: 5})
+		if want := filepath.Join(mnt, "c/Users/me/Docs"); err != nil || r.Resolved != want {
+			t.Fatalf("ResolveDetailed(%q) = %q, %v; want %q", in, r.Resolved, err, want)
+		}
+		if len(r.Ranked) < 2 || r.Ranked[0].Path != r.Resolved {
+			t.Errorf("ResolveDetailed(%q) ranked %+v; want the exact path first among its case variants", in, r.Ranked)
+		}
+	}
+}
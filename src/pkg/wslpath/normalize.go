@@ -0,0 +1,40 @@
+package wslpath
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Normalize returns the Linux form arg would take if every name in it existed exactly as typed, without
+// touching the filesystem: a Windows path is mapped under the first mount root with its separators turned
+// into slashes, and a Linux path has "~" and variables expanded and is cleaned. It never fails; input it
+// cannot make sense of is cleaned as it is. This is a display fallback for when resolution fails.
+func Normalize(arg, cwd, home string, opts Options) string {
+	opts.Physical = false // following symlinks would touch the filesystem
+	rs := newResolver(context.Background(), cwd, home, opts)
+	p, mode, err := rs.classify(arg)
+	if err != nil {
+		return filepath.Clean(strings.TrimSpace(arg))
+	}
+	switch mode {
+	case ModeWindows, ModeCollapsed:
+		return filepath.Join(append([]string{rs.opts.MountRoot, string(unicode.ToLower(rune(p[0])))}, windowsSegments(p[2:])...)...)
+	case ModeUNC:
+		return filepath.Join(append([]string{rs.opts.UNCRoot}, uncSegments(p)...)...)
+	case ModeWSL:
+		return filepath.Join(append([]string{"/"}, uncSegments(p)[2:]...)...)
+	case ModeRooted:
+		if drive, ok := rs.cwdDrive(); ok {
+			return filepath.Join(append([]string{rs.opts.MountRoot, string(unicode.ToLower(rune(drive)))}, windowsSegments(p)...)...)
+		}
+		return filepath.Join(append([]string{"/"}, windowsSegments(p)...)...)
+	case ModeRelative:
+		return filepath.Join(cwd, strings.ReplaceAll(p, "\\", "/"))
+	}
+	if lp, err := rs.resolveLinuxLike(p); err == nil {
+		return lp
+	}
+	return filepath.Clean(p)
+}
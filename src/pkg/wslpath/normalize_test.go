@@ -0,0 +1,24 @@
+package wslpath
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	opts := Options{MountRoot: "/mnt", UNCRoot: "/unc"}
+	tests := []struct{ in, want string }{
+		{`C:\Users\me\..\Public`, "/mnt/c/Users/Public"},
+		{`C:\..\..\Windows`, "/mnt/c/Windows"},
+		{`\\srv\share\dir`, "/unc/srv/share/dir"},
+		{`\\srv\share\a\..\x`, "/unc/srv/share/x"},
+		// ".." stops at the share, as on Windows, rather than climbing into the cwd.
+		{`\\srv\share\..\..\x`, "/unc/srv/share/x"},
+		{`//srv/share/../x`, "/unc/srv/share/x"},
+		{`\\wsl$\Ubuntu\home\..\..\etc`, "/etc"},
+		{"/var/log/../tmp", "/var/tmp"},
+		{`..\Shared`, "/work/Shared"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in, "/work/dir", "/home/me", opts); got != tt.want {
+			t.Errorf("Normalize(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
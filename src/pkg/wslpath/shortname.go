@@ -0,0 +1,80 @@
+package wslpath
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// isShortName reports whether seg looks like a generated Windows 8.3 short name, e.g. "PROGRA~1" or "LONGFI~2.TXT".
+func isShortName(seg string) bool {
+	_, _, ok := parseShortName(seg)
+	return ok
+}
+
+// parseShortName splits a generated short name into its uppercase name prefix and extension.
+func parseShortName(seg string) (prefix, ext string, ok bool) {
+	base, ext, _ := strings.Cut(seg, ".")
+	prefix, num, found := strings.Cut(base, "~")
+	if !found || prefix == "" || len(base) > 8 || len(ext) > 3 || strings.Contains(ext, ".") {
+		return "", "", false
+	}
+	if n, err := strconv.Atoi(num); err != nil || n < 1 {
+		return "", "", false
+	}
+	return strings.ToUpper(prefix), strings.ToUpper(ext), true
+}
+
+// shortForm returns the uppercase name part and extension Windows would derive a short name from:
+// spaces and inner dots dropped, characters not allowed in 8.3 names replaced by '_', and the extension
+// cut to three characters.
+func shortForm(name string) (base, ext string) {
+	name = strings.ToUpper(strings.ReplaceAll(name, " ", ""))
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name, ext = name[:i], name[i+1:]
+	}
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r == '.' {
+				return -1
+			}
+			if r > 0x7f || strings.ContainsRune(`"*+,/:;<=>?[\]|`, r) {
+				return '_'
+			}
+			return r
+		}, s)
+	}
+	base, ext = clean(name), clean(ext)
+	if len(ext) > 3 {
+		ext = ext[:3]
+	}
+	return base, ext
+}
+
+// shortNameMatches lists the entries of dir whose long names could have been given the short name seg.
+// The ~N index depends on the order the names were created in, so it is not used to tell them apart.
+// last is set for the final path segment, which may be a file with Options.Parent.
+func (rs *resolver) shortNameMatches(dir, seg string, ents []fs.DirEntry, last bool) []string {
+	prefix, ext, ok := parseShortName(seg)
+	if !ok {
+		return nil
+	}
+	var paths []string
+	for _, e := range ents {
+		n := e.Name()
+		if rs.ignored(n) || (!last && !rs.canDescend(e)) {
+			continue
+		}
+		base, nameExt := shortForm(n)
+		if !strings.HasPrefix(base, prefix) || nameExt != ext {
+			continue
+		}
+		full := filepath.Join(dir, n)
+		if isDir, err := rs.isDirFollowSymlink(full, e); err != nil || (!isDir && !(rs.opts.Parent && last)) {
+			continue
+		}
+		paths = append(paths, full)
+	}
+	return paths
+}
@@ -0,0 +1,614 @@
+package wslpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// wslSharePrefixes are the Explorer-style UNC hosts that point back into a WSL distro.
+var wslSharePrefixes = []string{"wsl$", "wsl.localhost"}
+
+// IsWSLSharePath detects paths like "\\wsl$\Ubuntu\..." or "//wsl.localhost/Ubuntu/...".
+func IsWSLSharePath(p string) bool {
+	if len(p) < 3 || !isSep(p[0]) || !isSep(p[1]) {
+		return false
+	}
+	segs := uncSegments(p)
+	if len(segs) < 2 {
+		return false
+	}
+	for _, host := range wslSharePrefixes {
+		if strings.EqualFold(segs[0], host) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveWSLSharePath strips the "\\\\wsl$\\<distro>" prefix and resolves the rest as an absolute Linux path.
+// A distro other than Options.Distro is warned about, since only the current distro's filesystem is visible.
+func (rs *resolver) resolveWSLSharePath(p string) (string, error) {
+	segs := uncSegments(p)
+	distro := segs[1]
+	if cur := rs.opts.Distro; cur != "" && !strings.EqualFold(cur, distro) {
+		rs.warnf("path refers to distro %q but this is %q; resolving from /", distro, cur)
+	}
+	lp, err := rs.resolveLinuxLike("/" + strings.Join(segs[2:], "/"))
+	if err != nil {
+		return "", err
+	}
+	return rs.verifyDir(lp)
+}
+
+// ToWindowsPath converts an absolute Linux path to its Windows form. Paths under <mountRoot>/<drive> become
+// "<DRIVE>:\...", anything else becomes "\\wsl$\<distro>\...". An empty mountRoot means DefaultMountRoot.
+func ToWindowsPath(linux, distro, mountRoot string) (string, error) {
+	if !filepath.IsAbs(linux) {
+		return "", fmt.Errorf("not an absolute path: %s", linux)
+	}
+	if mountRoot == "" {
+		mountRoot = DefaultMountRoot
+	}
+	p := filepath.Clean(linux)
+	if rel, ok := strings.CutPrefix(p, strings.TrimSuffix(mountRoot, "/")+"/"); ok {
+		drive, rest, _ := strings.Cut(rel, "/")
+		if len(drive) == 1 && unicode.IsLetter(rune(drive[0])) {
+			return strings.ToUpper(drive) + ":\\" + strings.ReplaceAll(rest, "/", "\\"), nil
+		}
+	}
+	if distro == "" {
+		return "", fmt.Errorf("%s is not under a Windows drive and WSL_DISTRO_NAME is not set", p)
+	}
+	return "\\\\wsl$\\" + distro + strings.TrimSuffix(strings.ReplaceAll(p, "/", "\\"), "\\"), nil
+}
+
+// IsWindowsPath detects drive-letter paths with separators like "C:\...", "d:/..." or "C:Users\me/Docs",
+// in any mix of '\\' and '/', and a bare drive like "C:".
+func IsWindowsPath(p string) bool {
+	if len(p) < 2 {
+		return false
+	}
+	// [A-Za-z]:
+	r0 := rune(p[0])
+	if !unicode.IsLetter(r0) {
+		return false
+	}
+	if p[1] != ':' {
+		return false
+	}
+	return len(p) == 2 || strings.ContainsAny(p[2:], `\/`)
+}
+
+// IsCollapsedWindowsPath detects inputs like "C:Something" where all the path separators were lost.
+func IsCollapsedWindowsPath(p string) bool {
+	if len(p) < 3 {
+		return false
+	}
+	if !unicode.IsLetter(rune(p[0])) || p[1] != ':' {
+		return false
+	}
+	return !strings.ContainsAny(p[2:], `\/`)
+}
+
+// resolveWindowsPath maps e.g. "C:\\Foo\\Bar" -> best matching "/mnt/c/Foo/Bar" using case-insensitive segment matching.
+func (rs *resolver) resolveWindowsPath(win string) (Resolution, error) {
+	segs := windowsSegments(win[2:]) // separators may be mixed, and the first may be missing
+
+	// wslpath knows the real mapping; the segments only need matching if its answer doesn't exist.
+	if rs.opts.Wslpath != "" {
+		if p, err := rs.resolveViaWslpath(win); err != nil {
+			rs.tracef(1, "%v", err)
+		} else if p, err = rs.verifyDir(p); err == nil {
+			return Resolution{Resolved: p}, nil
+		}
+	}
+
+	root, err := rs.mapDrive(win[0])
+	if err != nil && rs.opts.LooseDrive && len(segs) > 0 && errors.Is(err, ErrDriveMapping) {
+		return rs.resolveLooseDrive(win, err)
+	}
+	if err != nil {
+		return Resolution{}, err
+	}
+	if r, ok := rs.resolveExact(root, segs); ok {
+		return r, nil
+	}
+
+	return rs.resolveSegments(root, segs, win)
+}
+
+// resolveLooseDrive retries win, whose drive is not mounted, on every drive for Options.LooseDrive, in
+// case only the letter is wrong. It warns which drive it used; if no single drive has a match, the
+// original mapping error stands.
+func (rs *resolver) resolveLooseDrive(win string, mapErr error) (Resolution, error) {
+	r, err := rs.resolveAnyDrive(win[2:])
+	if err != nil {
+		rs.tracef(1, "no other drive has %s: %v", win[2:], err)
+		return Resolution{}, mapErr
+	}
+	used := r.Resolved
+	for _, mnt := range rs.mountRoots() {
+		if w, err := ToWindowsPath(r.Resolved, "", mnt); err == nil {
+			used = w[:2]
+			break
+		}
+	}
+	rs.warnf("drive %c: is not mounted; using %s instead: %s", unicode.ToUpper(rune(win[0])), used, r.Resolved)
+	return r, nil
+}
+
+// resolveExact is the fast path for input whose case is already right: it checks the segments joined as
+// typed, without listing any directory. It declines (ok=false) when the walk could have chosen differently,
+// and with Options.Explain, which wants the candidates the walk weighs.
+func (rs *resolver) resolveExact(root string, segs []string) (res Resolution, ok bool) {
+	if len(segs) == 0 || rs.opts.Explain > 0 || slices.ContainsFunc(segs, hasGlobMeta) || slices.ContainsFunc(segs, rs.ignored) {
+		return Resolution{}, false
+	}
+	p := filepath.Join(append([]string{root}, segs...)...)
+	if rs.opts.NoFollowSymlinks && checkNoSymlinks(p) != nil {
+		return Resolution{}, false
+	}
+	p, err := rs.verifyDir(p)
+	if err != nil {
+		return Resolution{}, false
+	}
+	score := 0
+	for _, s := range segs {
+		score += CaseScore(s, s)
+	}
+	rs.tracef(1, "exact path %s exists", p)
+	return Resolution{Resolved: p, Score: score}, true
+}
+
+// MapDrive returns the directory that Windows paths on drive letter are mapped to, e.g. 'C' -> "/mnt/c".
+// The returned entry exists under one of the mount roots but is not checked to be a directory.
+func MapDrive(letter byte, opts Options) (string, error) {
+	return newResolver(context.Background(), "", "", opts).mapDrive(letter)
+}
+
+// mapDrive locates the directory for a drive letter under the first mount root that has it, e.g. 'C' -> "/mnt/c".
+func (rs *resolver) mapDrive(letter byte) (string, error) {
+	if p, ok := rs.opts.DriveMap[byte(unicode.ToLower(rune(letter)))]; ok {
+		if info, err := rs.fs.stat(p); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("cannot locate %s, which drive %c is mapped to (%w): not a directory", p, unicode.ToUpper(rune(letter)), ErrDriveMapping)
+		}
+		rs.tracef(1, "drive %c is mapped to %s", unicode.ToUpper(rune(letter)), p)
+		return p, nil
+	}
+	if rs.opts.Wslpath != "" {
+		if p, err := rs.resolveViaWslpath(string(letter) + ":\\"); err != nil {
+			rs.tracef(1, "%v", err)
+		} else if info, err := rs.fs.stat(p); err == nil && info.IsDir() {
+			return p, nil
+		}
+	}
+	roots := rs.mountRoots()
+	drive := unicode.ToLower(rune(letter))
+	var errs []string
+	for i, mnt := range roots {
+		p, err := rs.mapDriveUnder(mnt, letter)
+		if err == nil {
+			if i > 0 {
+				rs.tracef(1, "drive %c not under %s; found %s", unicode.ToUpper(drive), strings.Join(roots[:i], ", "), p)
+			}
+			return p, nil
+		}
+		if cerr := rs.ctx.Err(); cerr != nil {
+			return "", fmt.Errorf("gave up locating %s: %w", filepath.Join(mnt, string(drive)), cerr)
+		}
+		if len(roots) == 1 {
+			return "", fmt.Errorf("cannot locate %s (%w): %v%s", filepath.Join(mnt, string(drive)), ErrDriveMapping, err, noDriveHint(drive))
+		}
+		errs = append(errs, err.Error())
+	}
+	return "", fmt.Errorf("cannot locate drive %c under %s (%w): %s%s", unicode.ToUpper(drive), strings.Join(roots, " or "), ErrDriveMapping, strings.Join(errs, "; "), noDriveHint(drive))
+}
+
+// noDriveHint follows the error for a drive letter with no directory under any mount root.
+func noDriveHint(drive rune) string {
+	return fmt.Sprintf("\nHint: drive %c: is not mounted in WSL; a network drive appears once it is mapped in Windows", unicode.ToUpper(drive))
+}
+
+// unmountedHint explains a failed walk beneath an empty drive or share directory, which is usually a mount
+// point whose drive isn't mounted yet rather than an empty drive. It returns "" if root has entries.
+func (rs *resolver) unmountedHint(root string) string {
+	if ents, err := rs.fs.readDir(root); err != nil || len(ents) > 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nHint: %s is empty, so its drive is probably not mounted yet; open it from Windows first (or mount it with sudo mount -t drvfs)", root)
+}
+
+// mapDriveUnder locates the directory for a drive letter under the mount root mnt.
+func (rs *resolver) mapDriveUnder(mnt string, letter byte) (string, error) {
+	drive := unicode.ToLower(rune(letter))
+	// Bind mounts can leave both /mnt/c and /mnt/C. Prefer the case that was typed, else lowercase.
+	if ents, err := rs.fs.readDir(mnt); err == nil {
+		var variants []string
+		for _, e := range ents {
+			if strings.EqualFold(e.Name(), string(drive)) {
+				variants = append(variants, filepath.Join(mnt, e.Name()))
+			}
+		}
+		if len(variants) > 1 {
+			pick := filepath.Join(mnt, string(drive))
+			if p := filepath.Join(mnt, string(letter)); slices.Contains(variants, p) {
+				pick = p
+			}
+			rs.warnf("drive %c is mounted more than once (%s); using %s", unicode.ToUpper(drive), strings.Join(variants, ", "), pick)
+			return pick, nil
+		}
+	}
+	name, err := rs.pickCaseInsensitiveEntry(mnt, string(drive))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(mnt, name), nil
+}
+
+// windowsSegments splits a Windows path tail on either separator, dropping empty and "." segments and applying "..".
+func windowsSegments(rest string) []string {
+	rest = strings.ReplaceAll(rest, "\\", "/")
+	var segs []string
+	for _, s := range strings.Split(rest, "/") {
+		if s == "" { continue }
+		if s == "." { continue }
+		if s == ".." { if len(segs) > 0 { segs = segs[:len(segs)-1] }; continue }
+		segs = append(segs, s)
+	}
+	return segs
+}
+
+// uncSegments splits a "\\server\share\..." path into the server, the share and the segments below it. As on
+// Windows, ".." stops at the share, so it can never climb out of it. It returns nil without both server and share.
+func uncSegments(p string) []string {
+	rest := strings.TrimLeft(strings.ReplaceAll(p, "\\", "/"), "/")
+	server, rest, _ := strings.Cut(rest, "/")
+	share, rest, _ := strings.Cut(strings.TrimLeft(rest, "/"), "/")
+	if server == "" || share == "" { return nil }
+	return append([]string{server, share}, windowsSegments(rest)...)
+}
+
+// resolveSegments walks segs case-insensitively beneath root and returns the best scoring directory.
+// win is the original input, used in error messages.
+func (rs *resolver) resolveSegments(root string, segs []string, win string) (Resolution, error) {
+	cands, deepest, err := rs.exploreCandidates(root, segs)
+	if err != nil { return Resolution{}, err }
+	if len(cands) == 0 {
+		if len(segs) == 0 {
+			p, err := rs.verifyDir(root)
+			return Resolution{Resolved: p}, err
+		}
+		if rs.opts.Nearest {
+			rs.tracef(1, "nearest match %s (%d of %d segments)", deepest.fullPath, deepest.depth, len(segs))
+			p, err := rs.verifyDir(deepest.fullPath)
+			return Resolution{Resolved: p, Score: deepest.score, Unmatched: len(segs) - deepest.depth, Missing: segs[deepest.depth:]}, err
+		}
+		if i := slices.IndexFunc(segs, isReservedName); i >= 0 {
+			return Resolution{}, reservedNameError(segs[i], win)
+		}
+		hint := rs.unmountedHint(root) + rs.suggestHint(deepest.fullPath, segs[deepest.depth], false)
+		if rs.opts.CaseSensitive {
+			return Resolution{}, fmt.Errorf("%w (no exact-case match): %s%s", ErrNotFound, win, hint)
+		}
+		return Resolution{}, fmt.Errorf("%w (no case-insensitive match): %s%s", ErrNotFound, win, hint)
+	}
+
+	deciders := rs.sortCandidates(cands)
+	for _, c := range cands { rs.tracef(1, "candidate %s (score=%d, fuzz=%d)", c.fullPath, c.score, c.fuzz) }
+	ranked := rs.explain(segs, cands, deciders)
+	// A glob segment must select a single directory rather than the best scoring one.
+	if len(cands) > 1 && slices.ContainsFunc(segs, hasGlobMeta) {
+		var paths []string
+		for _, c := range cands { paths = append(paths, c.fullPath) }
+		p, err := rs.pickOne(win, paths)
+		if err == nil { p, err = rs.verifyDir(p) }
+		return Resolution{Resolved: p, Candidates: paths, Considered: len(cands), Ranked: ranked}, err
+	}
+	best := cands[0].fullPath
+	var tied []string
+	for _, c := range cands {
+		if compareSegScores(c.segScores, cands[0].segScores) == 0 && c.fuzz == cands[0].fuzz { tied = append(tied, c.fullPath) }
+	}
+	if rs.opts.Choose != nil && len(tied) > 1 {
+		if best, err = rs.opts.Choose(tied); err != nil { return Resolution{}, err }
+	}
+	p, err := rs.verifyDir(best)
+	return Resolution{Resolved: p, Candidates: tied, Considered: len(cands), Score: cands[0].score, Ranked: ranked}, err
+}
+
+// explain describes the best Options.Explain of the sorted cands for Resolution.Ranked.
+func (rs *resolver) explain(segs []string, cands []candidate, deciders []string) []Ranked {
+	var ranked []Ranked
+	for i, c := range cands[:min(rs.opts.Explain, len(cands))] {
+		names := strings.Split(c.fullPath, "/")
+		ranked = append(ranked, Ranked{
+			Path:     c.fullPath,
+			Typed:    segs[len(segs)-len(c.segScores):],
+			Names:    names[len(names)-len(c.segScores):],
+			Scores:   c.segScores,
+			Fuzz:     c.fuzz,
+			Decision: deciders[i],
+		})
+	}
+	return ranked
+}
+
+// sortCandidates orders cands best first. Exact matches always outrank fuzzy ones; among equals the case
+// scores decide, deepest segment first, and then Options.TieBreak. It returns, for each candidate but the
+// last, the rule that ranked it above the next one.
+func (rs *resolver) sortCandidates(cands []candidate) []string {
+	// Depth is that of the real directory: candidates of one walk differ in depth only through symlinks.
+	depth := map[string]int{}
+	if rs.opts.TieBreak == TieBreakShallow || rs.opts.TieBreak == TieBreakDeep {
+		for _, c := range cands {
+			real, err := filepath.EvalSymlinks(c.fullPath)
+			if err != nil { real = c.fullPath }
+			depth[c.fullPath] = strings.Count(real, "/")
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		c, _ := rs.rank(cands[i], cands[j], depth)
+		return c < 0
+	})
+	deciders := make([]string, len(cands))
+	for i := 0; i+1 < len(cands); i++ {
+		_, deciders[i] = rs.rank(cands[i], cands[i+1], depth)
+	}
+	return deciders
+}
+
+// rank compares two candidates for sortCandidates: it returns <0 if a goes first, >0 if b does, and the
+// rule that decided, or "tied" if nothing tells them apart.
+func (rs *resolver) rank(a, b candidate, depth map[string]int) (int, string) {
+	if a.fuzz != b.fuzz {
+		return a.fuzz - b.fuzz, "fewer fuzzy edits"
+	}
+	for i := min(len(a.segScores), len(b.segScores)) - 1; i >= 0; i-- {
+		if a.segScores[i] != b.segScores[i] {
+			return b.segScores[i] - a.segScores[i], fmt.Sprintf("case score of segment %d", i+1)
+		}
+	}
+	if da, db := depth[a.fullPath], depth[b.fullPath]; da != db {
+		switch rs.opts.TieBreak {
+		case TieBreakShallow:
+			return da - db, "tie-break shallow"
+		case TieBreakDeep:
+			return db - da, "tie-break deep"
+		}
+	}
+	if c := strings.Compare(a.fullPath, b.fullPath); c != 0 {
+		return c, "tie-break lexical"
+	}
+	return 0, "tied"
+}
+
+// IsUNCPath detects UNC paths like "\\server\share\..." or "//server/share/...".
+func IsUNCPath(p string) bool {
+	if len(p) < 3 || !isSep(p[0]) || !isSep(p[1]) || isSep(p[2]) {
+		return false
+	}
+	return len(uncSegments(p)) >= 2
+}
+
+// IsRootedWindowsPath detects Windows paths rooted on the current drive, like "\Windows\System32":
+// a single leading backslash that isn't the start of a UNC path.
+func IsRootedWindowsPath(p string) bool {
+	return len(p) >= 1 && p[0] == '\\' && (len(p) == 1 || !isSep(p[1]))
+}
+
+// IsRelativeWindowsPath detects relative paths written with backslashes, like "..\..\Shared" or "src\app":
+// no drive, no leading separator, and at least one backslash.
+func IsRelativeWindowsPath(p string) bool {
+	return p != "" && !isSep(p[0]) && strings.Contains(p, "\\") && !IsWindowsPath(p) && !IsCollapsedWindowsPath(p)
+}
+
+// resolveRelativePath resolves a relative Windows path against the cwd. Leading ".." segments climb from
+// the cwd as typed; the remaining segments are matched case-insensitively like those of a drive path.
+func (rs *resolver) resolveRelativePath(p string) (Resolution, error) {
+	base := filepath.Clean(rs.cwd)
+	var segs []string
+	for _, s := range strings.Split(strings.ReplaceAll(p, "\\", "/"), "/") {
+		switch {
+		case s == "" || s == ".":
+		case s == ".." && len(segs) == 0:
+			base = filepath.Dir(base)
+		case s == "..":
+			segs = segs[:len(segs)-1]
+		default:
+			segs = append(segs, s)
+		}
+	}
+	rs.tracef(1, "relative to %s", base)
+	if r, ok := rs.resolveExact(base, segs); ok {
+		return r, nil
+	}
+	return rs.resolveSegments(base, segs, p)
+}
+
+// resolveRootedPath resolves a driveless Windows path on the drive the cwd is on, as Windows would.
+func (rs *resolver) resolveRootedPath(p string) (Resolution, error) {
+	drive, ok := rs.cwdDrive()
+	if !ok {
+		return Resolution{}, fmt.Errorf("cannot resolve %s: a Windows path without a drive is relative to the current drive, but %s is not on a drive under %s (%w)", p, rs.cwd, strings.Join(rs.mountRoots(), " or "), ErrDriveMapping)
+	}
+	rs.tracef(1, "current directory is on drive %c", unicode.ToUpper(rune(drive)))
+	return rs.resolveWindowsPath(string(drive) + ":" + p)
+}
+
+// cwdDrive returns the drive letter the cwd is mounted from, if it is under <mount root>/<drive>.
+func (rs *resolver) cwdDrive() (byte, bool) {
+	for _, mnt := range rs.mountRoots() {
+		rel, ok := strings.CutPrefix(filepath.Clean(rs.cwd)+"/", strings.TrimSuffix(mnt, "/")+"/")
+		if !ok {
+			continue
+		}
+		drive, _, _ := strings.Cut(rel, "/")
+		if len(drive) == 1 && unicode.IsLetter(rune(drive[0])) {
+			return drive[0], true
+		}
+	}
+	return 0, false
+}
+
+// trimLongPathPrefix strips the \\?\ long-path prefix, with either separator: "\\?\C:\Foo" becomes "C:\Foo"
+// and "\\?\UNC\server\share" becomes "\\server\share".
+func trimLongPathPrefix(p string) (string, bool) {
+	if len(p) < 4 || !isSep(p[0]) || !isSep(p[1]) || p[2] != '?' || !isSep(p[3]) {
+		return p, false
+	}
+	rest := p[4:]
+	if len(rest) >= 4 && strings.EqualFold(rest[:3], "UNC") && isSep(rest[3]) {
+		return rest[3:4] + rest[3:], true
+	}
+	return rest, true
+}
+
+// driveFromDoubleSlash rewrites the "//c/Users" form Git Bash and MSYS tools print (or "\\c\Users") to
+// "c:/Users". Only a single letter counts as a drive; "//srv/share" is a UNC path to server "srv".
+func driveFromDoubleSlash(p string) (string, bool) {
+	if len(p) < 3 || !isSep(p[0]) || !isSep(p[1]) || !unicode.IsLetter(rune(p[2])) || p[2] > unicode.MaxASCII {
+		return p, false
+	}
+	if len(p) > 3 && !isSep(p[3]) {
+		return p, false
+	}
+	return p[2:3] + ":/" + strings.TrimLeft(p[3:], "\\/"), true
+}
+
+// pathFromFileURL turns a file:// URL into the path it names, percent-decoded: "file:///mnt/c/My%20Docs"
+// gives "/mnt/c/My Docs", "file:///C:/Users" gives "C:/Users", and a host other than localhost makes a
+// UNC path, so "file://wsl.localhost/Ubuntu/home" gives "\\wsl.localhost\Ubuntu\home". ok is false for
+// anything that isn't a file URL.
+func pathFromFileURL(s string) (p string, ok bool, err error) {
+	if len(s) < len("file://") || !strings.EqualFold(s[:len("file://")], "file://") {
+		return s, false, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", true, fmt.Errorf("malformed file URL: %v", err)
+	}
+	if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+		return `\\` + u.Host + strings.ReplaceAll(u.Path, "/", `\`), true, nil
+	}
+	if len(u.Path) > 1 && IsWindowsPath(u.Path[1:]) {
+		return u.Path[1:], true, nil
+	}
+	return u.Path, true, nil
+}
+
+// isReservedName reports whether seg is a Windows device name such as "CON", "nul" or "COM1.txt",
+// which Windows never lets a directory be called.
+func isReservedName(seg string) bool {
+	base, _, _ := strings.Cut(seg, ".")
+	base = strings.ToUpper(strings.TrimRight(base, " "))
+	switch base {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	return len(base) == 4 && (strings.HasPrefix(base, "COM") || strings.HasPrefix(base, "LPT")) && base[3] >= '1' && base[3] <= '9'
+}
+
+// reservedNameError explains that path can't resolve because seg is a Windows device name.
+func reservedNameError(seg, path string) error {
+	return fmt.Errorf("%w: %s is a Windows device name, which has no directory on the Linux side: %s", ErrNotFound, seg, path)
+}
+
+func isSep(c byte) bool { return c == '\\' || c == '/' }
+
+// resolveUNCPath maps e.g. "\\\\server\\share\\Foo" -> best matching "/mnt/server/share/Foo".
+// The server and share are located case-insensitively; the remaining segments are walked like a drive path.
+func (rs *resolver) resolveUNCPath(unc string) (Resolution, error) {
+	segs := uncSegments(unc)
+	server, share := segs[0], segs[1]
+	base := rs.opts.UNCRoot
+
+	srv, err := rs.pickCaseInsensitiveEntry(base, server)
+	if err != nil {
+		return Resolution{}, fmt.Errorf("cannot locate mount for \\\\%s\\%s under %s (%w): %v", server, share, base, ErrDriveMapping, err)
+	}
+	shr, err := rs.pickCaseInsensitiveEntry(filepath.Join(base, srv), share)
+	if err != nil {
+		return Resolution{}, fmt.Errorf("cannot locate mount for \\\\%s\\%s under %s (%w): %v", server, share, base, ErrDriveMapping, err)
+	}
+	root := filepath.Join(base, srv, shr)
+
+	return rs.resolveSegments(root, segs[2:], unc)
+}
+
+// resolveWindowsPathCollapsed matches directory names as case-insensitive prefixes of the tail, longest first,
+// backtracking to the next-best name when a choice leads to a dead end further down.
+// Any separators left in the tail, as in "C:JunkProjects\\MyRepo", split it into runs matched separately.
+func (rs *resolver) resolveWindowsPathCollapsed(win string) (Resolution, error) {
+	curr, err := rs.mapDrive(win[0])
+	if err != nil {
+		return Resolution{}, err
+	}
+	if tail := strings.TrimLeft(win[2:], "\\/"); tail != "" {
+		if hint := rs.unmountedHint(curr); hint != "" {
+			return Resolution{}, fmt.Errorf("%w '%s' under %s%s", ErrUnsegmentable, tail, curr, hint)
+		}
+	}
+	considered := 0
+	res, err := rs.segmentCollapsed(curr, win[2:], 0, 0, &considered)
+	res.Considered = considered
+	return res, err
+}
+
+// segmentCollapsed resolves tail beneath curr, trying the matches for its head in collapsedMatches order
+// until one leads to a full resolution. If none does, the error is that of the first (greediest) choice,
+// or with Options.Nearest its partial resolution.
+// depth counts the directories descended so far, which may not exceed Options.MaxDepth.
+func (rs *resolver) segmentCollapsed(curr, tail string, depth, score int, considered *int) (Resolution, error) {
+	tail = strings.TrimLeft(tail, "\\/")
+	if len(tail) == 0 {
+		p, err := rs.verifyDir(curr)
+		return Resolution{Resolved: p, Score: score}, err
+	}
+	if err := rs.ctx.Err(); err != nil { return Resolution{}, fmt.Errorf("gave up segmenting '%s' under %s: %w", tail, curr, err) }
+	if depth >= rs.maxDepth() { return Resolution{}, tooDeepError(curr, rs.maxDepth()) }
+
+	// An explicit separator is a hard boundary: names are matched within the text before it.
+	chunk := tail
+	if k := strings.IndexAny(tail, "\\/"); k >= 0 { chunk = tail[:k] }
+	ms, err := rs.collapsedMatches(curr, chunk, len(chunk) < len(tail))
+	if err != nil { return Resolution{}, err }
+
+	if len(ms) == 0 && rs.opts.Nearest {
+		rs.tracef(1, "cannot segment %q under %s; stopping there", tail, curr)
+		p, err := rs.verifyDir(curr)
+		return Resolution{Resolved: p, Score: score, Unmatched: 1, Missing: windowsSegments(tail)}, err
+	}
+	if len(ms) == 0 {
+		if isReservedName(chunk) {
+			return Resolution{}, reservedNameError(chunk, tail)
+		}
+		if rs.opts.CaseSensitive {
+			return Resolution{}, fmt.Errorf("%w '%s' at '%s' under %s: no exact-case match%s", ErrUnsegmentable, tail, argHead(tail), curr, rs.suggestHint(curr, chunk, true))
+		}
+		return Resolution{}, fmt.Errorf("%w '%s' at '%s' under %s\nHint: quote the Windows path or use forward slashes (e.g., C:/...)%s", ErrUnsegmentable, tail, argHead(tail), curr, rs.suggestHint(curr, chunk, true))
+	}
+
+	*considered += len(ms)
+	var first Resolution
+	var firstErr error
+	for i, m := range ms {
+		if i == 0 {
+			for _, o := range ms[1:] { rs.tracef(2, "  passed over %q (plen=%d, score=%d)", o.name, o.plen, o.score) }
+			rs.tracef(1, "segment %q under %s (plen=%d, score=%d)", m.name, curr, m.plen, m.score)
+		} else {
+			rs.tracef(1, "backtracking: segment %q under %s (plen=%d, score=%d)", m.name, curr, m.plen, m.score)
+		}
+		res, err := rs.segmentCollapsed(filepath.Join(curr, m.name), tail[m.plen:], depth+1, score+m.score, considered)
+		if err == nil && res.Unmatched == 0 { return res, nil }
+		if rs.ctx.Err() != nil { return res, err }
+		if i == 0 { first, firstErr = res, err }
+	}
+	return first, firstErr
+}
@@ -0,0 +1,193 @@
+package wslpath
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDriveMountedTwice(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Work", "C/Work")
+	tests := []struct{ in, want string }{
+		{`C:\work`, "C/Work"}, // the case typed
+		{`c:\work`, "c/Work"},
+	}
+	for _, tt := range tests {
+		var warn strings.Builder
+		got, err := ResolveTarget(tt.in, "/", "/", Options{MountRoot: mnt, Warn: &warn})
+		if want := filepath.Join(mnt, tt.want); err != nil || got != want {
+			t.Errorf("ResolveTarget(%q) = %q, %v; want %q", tt.in, got, err, want)
+		}
+		if w := warn.String(); strings.Count(w, "\n") != 1 || !strings.Contains(w, filepath.Join(mnt, "c")) || !strings.Contains(w, filepath.Join(mnt, "C")) {
+			t.Errorf("ResolveTarget(%q) warned %q; want one line naming both drive directories", tt.in, w)
+		}
+	}
+}
+
+func TestCollapsedWithSeparators(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Foo/Bar/Baz", "c/FooBar/Baz", "c/Junk/Projects/MyRepo")
+	tests := []struct{ in, want, mode string }{
+		{`C:Foo\Bar`, "Foo/Bar", ModeWindows},
+		// "FooBar" would match were the separator not a boundary, and greedily win.
+		{`C:Foo\BarBaz`, "Foo/Bar/Baz", ModeCollapsed},
+		{`C:FooBar\Baz`, "FooBar/Baz", ModeWindows},
+		{`C:JunkProjects\MyRepo`, "Junk/Projects/MyRepo", ModeCollapsed},
+		{`C:Junk/ProjectsMyRepo`, "Junk/Projects/MyRepo", ModeCollapsed},
+		{`c:junk\projects/myrepo`, "Junk/Projects/MyRepo", ModeWindows},
+	}
+	for _, tt := range tests {
+		r, err := ResolveDetailed(tt.in, "/", "/", Options{MountRoot: mnt})
+		if want := filepath.Join(mnt, "c", tt.want); err != nil || r.Resolved != want || r.Mode != tt.mode {
+			t.Errorf("ResolveDetailed(%q) = %q (%s), %v; want %q (%s)", tt.in, r.Resolved, r.Mode, err, want, tt.mode)
+		}
+	}
+	// Nor is a name matched across one.
+	if got, err := ResolveTarget(`C:Fo\oBar`, "/", "/", Options{MountRoot: mnt}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolveTarget(C:Fo\\oBar) = %q, %v; want ErrNotFound", got, err)
+	}
+}
+
+func TestLongPathPrefix(t *testing.T) {
+	tests := []struct{ in, want, mode string }{
+		{`\\?\C:\Very\Long\Path`, `C:\Very\Long\Path`, ModeWindows},
+		{`\\?\C:\`, `C:\`, ModeWindows},
+		{`//?/C:/Very/Long`, `C:/Very/Long`, ModeWindows},
+		{`\\?/c:\Very`, `c:\Very`, ModeWindows},
+		{`\\?\UNC\server\share\dir`, `\\server\share\dir`, ModeUNC},
+		{`\\?\unc\server\share`, `\\server\share`, ModeUNC},
+		{`//?/UNC/server/share`, `//server/share`, ModeUNC},
+		{`\\?\UNC\wsl$\Ubuntu\home`, `\\wsl$\Ubuntu\home`, ModeWSL},
+	}
+	for _, tt := range tests {
+		if got, ok := trimLongPathPrefix(tt.in); !ok || got != tt.want {
+			t.Errorf("trimLongPathPrefix(%q) = %q, %v; want %q, true", tt.in, got, ok, tt.want)
+		}
+		if mode, err := DetectMode(tt.in, Options{}); err != nil || mode != tt.mode {
+			t.Errorf("DetectMode(%q) = %q, %v; want %q", tt.in, mode, err, tt.mode)
+		}
+	}
+	for _, in := range []string{`C:\Foo`, `\\server\share`, `\\?`, `\\.\C:\Foo`, `?\C:\Foo`} {
+		if got, ok := trimLongPathPrefix(in); ok {
+			t.Errorf("trimLongPathPrefix(%q) = %q, true; want no prefix", in, got)
+		}
+	}
+
+	mnt := mkdirs(t, t.TempDir(), "c/Very/Long/Path", "srv/share/dir")
+	for in, want := range map[string]string{
+		`\\?\c:\very\long\path`: "c/Very/Long/Path",
+		`\\?\UNC\SRV\Share\Dir`: "srv/share/dir",
+		`//?/UNC/srv/share/dir`: "srv/share/dir",
+	} {
+		got, err := ResolveTarget(in, "/", "/", Options{MountRoot: mnt, UNCRoot: mnt})
+		if want := filepath.Join(mnt, want); err != nil || got != want {
+			t.Errorf("ResolveTarget(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"file:///mnt/c/My%20Docs/a%23b", "/mnt/c/My Docs/a#b"},
+		{"FILE:///mnt/c/Users", "/mnt/c/Users"},
+		{"file://localhost/mnt/c/Users", "/mnt/c/Users"},
+		{"file:///C:/Program%20Files", "C:/Program Files"},
+		{"file:///c:", "c:"},
+		{"file://wsl.localhost/Ubuntu/home/me", `\\wsl.localhost\Ubuntu\home\me`},
+		{"file://server/share/My%20Dir", `\\server\share\My Dir`},
+		{"file:///home/%25USERPROFILE%25", "/home/%USERPROFILE%"},
+	}
+	for _, tt := range tests {
+		if got, ok, err := pathFromFileURL(tt.in); err != nil || !ok || got != tt.want {
+			t.Errorf("pathFromFileURL(%q) = %q, %v, %v; want %q", tt.in, got, ok, err, tt.want)
+		}
+	}
+	for _, in := range []string{"/mnt/c", `C:\Users`, "file:/mnt/c", "https://example.com/x"} {
+		if got, ok, err := pathFromFileURL(in); ok || err != nil || got != in {
+			t.Errorf("pathFromFileURL(%q) = %q, %v, %v; want it left alone", in, got, ok, err)
+		}
+	}
+	if _, ok, err := pathFromFileURL("file:///mnt/c/%zz"); !ok || err == nil {
+		t.Errorf("pathFromFileURL(bad escape) = %v, %v; want a malformed URL error", ok, err)
+	}
+
+	mnt := mkdirs(t, t.TempDir(), "c/My Docs")
+	lin := mkdirs(t, t.TempDir(), "with space/sub")
+	for in, want := range map[string]string{
+		"file:///C:/my%20docs":                filepath.Join(mnt, "c/My Docs"),
+		"file://" + lin + "/with%20space/sub": filepath.Join(lin, "with space/sub"),
+		"file://wsl.localhost/Ubuntu" + lin:   lin,
+	} {
+		r, err := ResolveDetailed(in, "/", "/", Options{MountRoot: mnt, UNCRoot: mnt})
+		if err != nil || r.Resolved != want {
+			t.Errorf("ResolveDetailed(%q) = %q, %v; want %q", in, r.Resolved, err, want)
+		}
+	}
+}
+
+func TestCollapsedDigitBoundaries(t *testing.T) {
+	tests := []struct {
+		dirs []string
+		in   string
+		want string
+	}{
+		{[]string{"App2/Data"}, "c:App2Data", "App2/Data"},
+		{[]string{"App/2Data"}, "c:App2Data", "App/2Data"},
+		// The greedy App2 is a dead end, so the walk backs up to App.
+		{[]string{"App2/Logs", "App/2Data"}, "c:app2data", "App/2Data"},
+		{[]string{"App2", "App/2Data"}, "c:App2Data", "App/2Data"},
+		// Both complete: the longer name wins.
+		{[]string{"App2/Data", "App/2Data"}, "c:App2Data", "App2/Data"},
+		// Backing up more than one level.
+		{[]string{"V12/Beta/Y", "V1/2Beta/X"}, "c:V12BetaX", "V1/2Beta/X"},
+		{[]string{"Build1/2024/Q1", "Build12/024"}, "c:build12024q1", "Build1/2024/Q1"},
+	}
+	for _, tt := range tests {
+		mnt := t.TempDir()
+		for _, d := range tt.dirs {
+			mkdirs(t, mnt, "c/"+d)
+		}
+		r, err := ResolveDetailed(tt.in, "/", "/", Options{MountRoot: mnt})
+		if want := filepath.Join(mnt, "c", tt.want); err != nil || r.Resolved != want || r.Mode != ModeCollapsed {
+			t.Errorf("ResolveDetailed(%q) among %v = %q (%s), %v; want %q", tt.in, tt.dirs, r.Resolved, r.Mode, err, want)
+		}
+	}
+
+	mnt := mkdirs(t, t.TempDir(), "c/App2/Data", "c/App/2Data")
+	if got, err := ResolveTarget("c:App2Date", "/", "/", Options{MountRoot: mnt}); !errors.Is(err, ErrUnsegmentable) || !strings.Contains(err.Error(), "'Date'") {
+		t.Errorf("ResolveTarget(c:App2Date) = %q, %v; want ErrUnsegmentable at the greedy choice", got, err)
+	}
+}
+
+func TestDriveMap(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "z/Shared/Docs")
+	shared := mkdirs(t, t.TempDir(), "Team/Reports/2024", "team/reports")
+	opts := Options{MountRoot: mnt, DriveMap: map[byte]string{'z': shared, 'y': filepath.Join(shared, "Team")}}
+	tests := []struct{ in, want string }{
+		{`Z:\`, shared},
+		{`z:\TEAM\Reports\2024`, filepath.Join(shared, "Team/Reports/2024")}, // walked case-insensitively
+		{`Z:\TEAM\reports`, filepath.Join(shared, "team/reports")},
+		{`Y:/reports`, filepath.Join(shared, "Team/Reports")},
+		{`y:Reports2024`, filepath.Join(shared, "Team/Reports/2024")}, // collapsed
+		{`C:\Shared`, ""}, // only mapped drives move
+	}
+	for _, tt := range tests {
+		got, err := ResolveTarget(tt.in, "/", "/", opts)
+		if tt.want == "" {
+			if !errors.Is(err, ErrDriveMapping) {
+				t.Errorf("ResolveTarget(%q) = %q, %v; want ErrDriveMapping", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveTarget(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	// The mapping hides the drive under the mount root.
+	if got, err := ResolveTarget(`Z:\shared`, "/", "/", opts); err == nil {
+		t.Errorf("ResolveTarget(Z:\\shared) = %q; want the mapped Z: without a Shared directory", got)
+	}
+	if got, err := MapDrive('Z', opts); err != nil || got != shared {
+		t.Errorf("MapDrive(Z) = %q, %v; want %q", got, err, shared)
+	}
+}
@@ -0,0 +1,338 @@
+// Package wslpath resolves Linux and Windows-style paths to directories on a WSL system.
+//
+// Linux paths behave like cd. Windows paths ("C:\Users\me", "\\server\share", "\\wsl$\Ubuntu\home")
+// are mapped under the automount root and their segments are matched case-insensitively, preferring
+// the candidate whose case best matches the input.
+package wslpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultMountRoot is where WSL mounts Windows drives unless wsl.conf says otherwise.
+const DefaultMountRoot = "/mnt"
+
+// DefaultMaxCandidates is how many tied branches a Windows path walk explores before it continues greedily.
+const DefaultMaxCandidates = 256
+
+// DefaultMaxDepth is how many directories deep a Windows path walk may descend before it gives up.
+const DefaultMaxDepth = 256
+
+// Errors wrapped by resolution failures, for errors.Is.
+var (
+	ErrNotFound      = errors.New("path does not exist")
+	ErrNotDirectory  = errors.New("not a directory")
+	ErrDriveMapping  = errors.New("drive mapping")
+	ErrAmbiguous     = errors.New("ambiguous") // several directories match and Options.Choose is unset
+	ErrUnsegmentable = errors.New("cannot segment")
+	ErrTooDeep       = errors.New("path too deep")            // a Windows path walk went past Options.MaxDepth
+	ErrNoTarget      = errors.New("missing target directory") // the input is empty once quotes and line breaks are dropped
+)
+
+// Input modes reported in Resolution.Mode, one per resolution branch.
+const (
+	ModeLinux     = "linux"
+	ModeWindows   = "windows"
+	ModeRelative  = "relative" // a Windows path relative to the cwd, like "..\\Shared"
+	ModeRooted    = "rooted"   // a Windows path without a drive, like "\Windows", on the drive of the cwd
+	ModeCollapsed = "collapsed"
+	ModeUNC       = "unc"
+	ModeWSL       = "wsl"
+	ModeAny       = "any"   // a drive-less Windows path searched on every drive, see ResolveAnyDrive
+	ModeLabel     = "label" // a drive-less Windows path beneath a volume mounted by its label, see ResolveLabel
+)
+
+// Tie-break strategies for Options.TieBreak, ordering candidates whose case scores are equal.
+const (
+	TieBreakLexical = "lexical" // the lexicographically first path
+	TieBreakShallow = "shallow" // the directory nearest the filesystem root once symlinks are followed, then lexical
+	TieBreakDeep    = "deep"    // the directory furthest from the filesystem root once symlinks are followed, then lexical
+)
+
+// Options controls resolution. The zero value resolves like a plain `wslcd <path>`.
+type Options struct {
+	MountRoot  string   // directory Windows drives are mounted under; DefaultMountRoot if empty
+	MountRoots []string // further mount roots tried in order for a drive not found under MountRoot, e.g. "/"
+	UNCRoot    string   // directory UNC shares are mounted under as <root>/<server>/<share>; DefaultMountRoot if empty
+	Distro     string   // running WSL distro, used to warn about \\wsl$ paths into another distro
+	SearchPath []string // directories a relative Linux path is looked up in when it is not under the cwd, like CDPATH
+	Wslpath    string   // wslpath utility to ask for drive mappings before falling back to MountRoot; unused if empty
+
+	Parent           bool   // a path to a file resolves to the directory containing it
+	Fuzzy            bool   // fall back to approximate matching for Windows path segments
+	CaseSensitive    bool   // require exact-case matches for drive letters and Windows path segments
+	NoFollowSymlinks bool   // don't descend through symlinked directories; only the final component may be a symlink
+	Physical         bool   // resolve ".." in Linux paths after following symlinks, like cd -P, rather than lexically
+	ExcludeDrives    string // drive letters skipped when every drive is searched, e.g. "de"; never affects an explicit drive
+	ExpandShortNames bool   // expand Windows 8.3 short names like "PROGRA~1" in path segments to the long name
+	Nearest          bool   // resolve a path that doesn't exist to its deepest existing ancestor, see Resolution.Unmatched
+	JoinLines        bool   // drop newlines inside the input, joining a path wrapped over several lines; CRs are always dropped
+	Suggest          bool   // add the names closest to a Windows path segment that matched nothing to the error
+	TrimTrailing     bool   // when a Windows path segment has no match, retry ignoring trailing spaces and dots on both sides
+	ReparseAsDir     bool   // take an entry that stat fails on with EIO but Lstat shows, e.g. a OneDrive placeholder, for a directory
+	LooseDrive       bool   // retry a path on a drive that isn't mounted on every drive, using the one drive that has it
+	StrictPerms      bool   // fail instead of warning when the resolved directory cannot be listed and entered
+	BreadthFirst     bool   // walk a Windows path level by level rather than depth-first; see exploreCandidates
+	ThroughArchives  bool   // descend into an archive file matched by a segment where a FUSE mount of it exists
+	FirstMatch       bool   // settle for the first directory a Windows path walk reaches instead of the best scored one
+	MaxCandidates    int    // branches explored before following only the best match at each level; DefaultMaxCandidates if <= 0
+	Explain          int    // best candidates of a Windows path walk described in Resolution.Ranked, with why each won
+	MaxDepth         int    // directories a walk may descend before giving up with an error; DefaultMaxDepth if <= 0
+	Parallel         int    // directories listed concurrently when a walk branches; one at a time if <= 1
+	TieBreak         string // how equally scored candidates are ordered, one of the TieBreak constants; lexical if empty
+
+	// Ignore lists directory name patterns (filepath.Match syntax, case-insensitive) that are never
+	// matched as Windows path segments, e.g. "node_modules" or "$Recycle.Bin".
+	Ignore []string
+
+	// DriveMap maps lowercase drive letters to the directories those drives are mounted on, e.g. 'z' to a
+	// bind mount at "/srv/shared". It is consulted before the mount roots.
+	DriveMap map[byte]string
+
+	// Choose picks one of several equally good directories. If nil, ties resolve to the first
+	// in sorted order and an ambiguous glob is an error.
+	Choose func(paths []string) (string, error)
+	// LookupEnv is used to expand variable references; os.LookupEnv if nil.
+	LookupEnv func(name string) (string, bool)
+	// Tracef receives resolution decisions, with higher levels giving more detail; nil discards them.
+	Tracef func(level int, format string, a ...any)
+	// Warn receives warnings that don't stop resolution; nil discards them.
+	Warn io.Writer
+}
+
+// Resolution describes a resolved target: which branch handled it, the candidates tied for the
+// top score, how many matching paths were weighed, and the winning score.
+type Resolution struct {
+	Input      string   `json:"input"`
+	Resolved   string   `json:"resolved"`
+	Mode       string   `json:"mode"`
+	Candidates []string `json:"candidates"`
+	Considered int      `json:"considered"`
+	Score      int      `json:"score"`
+	Unmatched  int      `json:"unmatched,omitempty"` // trailing segments left unresolved with Options.Nearest
+	Missing    []string `json:"missing,omitempty"`   // those segments' names as typed, e.g. to create them
+	Ranked     []Ranked `json:"ranked,omitempty"`    // the best candidates, with Options.Explain
+}
+
+// Ranked explains the place of one candidate of a Windows path walk: the CaseScore of each matched
+// segment, typed and named, and what put it ahead of the candidate after it.
+type Ranked struct {
+	Path     string   `json:"path"`
+	Typed    []string `json:"typed"`
+	Names    []string `json:"names"`
+	Scores   []int    `json:"scores"`
+	Fuzz     int      `json:"fuzz"`               // total edit distance of fuzzily matched segments
+	Decision string   `json:"decision,omitempty"` // rule that ranked it above the next candidate; "" for the last
+}
+
+// ResolveTarget resolves arg either as a Linux path or a Windows path mapped under the mount root.
+// Relative Linux paths are resolved against cwd and "~" against home.
+// Returns an absolute path to an existing directory.
+func ResolveTarget(arg, cwd, home string, opts Options) (string, error) {
+	r, err := ResolveDetailed(arg, cwd, home, opts)
+	return r.Resolved, err
+}
+
+// ResolveDetailed is ResolveTarget, reporting how the target was resolved.
+func ResolveDetailed(arg, cwd, home string, opts Options) (Resolution, error) {
+	return ResolveDetailedContext(context.Background(), arg, cwd, home, opts)
+}
+
+// ResolveDetailedContext is ResolveDetailed, giving up on walking the filesystem when ctx is done.
+// A Windows path walk that was cut short still settles on the best candidate found so far, if any.
+func ResolveDetailedContext(ctx context.Context, arg, cwd, home string, opts Options) (Resolution, error) {
+	return newResolver(ctx, cwd, home, opts).resolve(arg)
+}
+
+// DetectMode reports which resolution branch arg would take, one of the Mode constants, without touching
+// the filesystem. The mode of a resolution can still differ: a relative Windows path that exists as a Linux
+// name resolves as ModeLinux, and a Windows path whose segments don't match may fall back to ModeCollapsed.
+// An unset %VAR% is left as typed rather than failing, since only resolving needs its value.
+func DetectMode(arg string, opts Options) (string, error) {
+	lookup := opts.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	opts.LookupEnv = func(name string) (string, bool) {
+		if v, ok := lookup(name); ok {
+			return v, true
+		}
+		return "%" + name + "%", true
+	}
+	_, mode, err := newResolver(context.Background(), "", "", opts).classify(arg)
+	return mode, err
+}
+
+// ResolveLinuxLike expands variables and "~", makes arg absolute against cwd, and cleans it,
+// without touching the filesystem.
+func ResolveLinuxLike(arg, cwd, home string, opts Options) (string, error) {
+	return newResolver(context.Background(), cwd, home, opts).resolveLinuxLike(arg)
+}
+
+// resolver carries the options and environment for a single resolution.
+type resolver struct {
+	ctx       context.Context
+	opts      Options
+	cwd, home string
+	fs        *dirCache
+}
+
+func newResolver(ctx context.Context, cwd, home string, opts Options) *resolver {
+	if opts.MountRoot == "" {
+		opts.MountRoot = DefaultMountRoot
+	}
+	if opts.UNCRoot == "" {
+		opts.UNCRoot = DefaultMountRoot
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	return &resolver{ctx: ctx, opts: opts, cwd: cwd, home: home, fs: newDirCache(ctx)}
+}
+
+func (rs *resolver) tracef(level int, format string, a ...any) {
+	if rs.opts.Tracef != nil {
+		rs.opts.Tracef(level, format, a...)
+	}
+}
+
+// mountRoots lists the directories drives may be mounted under, in the order they are tried.
+func (rs *resolver) mountRoots() []string {
+	return append([]string{rs.opts.MountRoot}, rs.opts.MountRoots...)
+}
+
+func (rs *resolver) warnf(format string, a ...any) {
+	if rs.opts.Warn != nil {
+		fmt.Fprintf(rs.opts.Warn, "warning: "+format+"\n", a...)
+	}
+}
+
+// classify normalizes input, dropping line breaks, expanding %VAR% references and rewriting alternative drive forms, and
+// detects its mode. It does not touch the filesystem.
+func (rs *resolver) classify(input string) (arg, mode string, err error) {
+	// Text wrapped by the app it was copied from can carry line breaks, which no real path contains.
+	arg = strings.ReplaceAll(input, "\r", "")
+	if rs.opts.JoinLines {
+		arg = strings.ReplaceAll(arg, "\n", "")
+	}
+	arg = unquote(strings.TrimSpace(arg))
+	if arg == "" {
+		return "", "", ErrNoTarget
+	}
+	// Decode a file:// URL before %VAR% expansion could mistake its escapes for variables.
+	if p, ok, err := pathFromFileURL(arg); err != nil {
+		return "", "", err
+	} else if ok {
+		rs.tracef(1, "file URL path: %s", p)
+		arg = p
+	}
+	// Expand %VAR% first so e.g. %USERPROFILE% can turn into a drive-letter path.
+	arg, err = rs.expandVars(arg, '%')
+	if err != nil {
+		return "", "", err
+	}
+
+	if p, ok := driveFromDoubleSlash(arg); ok {
+		rs.tracef(1, "double-slash drive path: %s", p)
+		arg = p
+	}
+	if p, ok := trimLongPathPrefix(arg); ok {
+		rs.tracef(1, "dropped long-path prefix: %s", p)
+		arg = p
+	}
+	mode = detectMode(arg)
+	rs.tracef(1, "input %q: %s path", arg, mode)
+	return arg, mode, nil
+}
+
+func (rs *resolver) resolve(input string) (Resolution, error) {
+	arg, mode, err := rs.classify(input)
+	if err != nil {
+		return Resolution{Input: input}, err
+	}
+
+	var res Resolution
+	switch mode {
+	case ModeWSL:
+		res.Resolved, err = rs.resolveWSLSharePath(arg)
+	case ModeUNC:
+		res, err = rs.resolveUNCPath(arg)
+	case ModeRelative:
+		// A Linux name may legally contain backslashes, so a path that exists as typed wins.
+		if r, lerr := rs.resolveLinux(arg); lerr == nil {
+			rs.tracef(1, "exists as a Linux path")
+			res, mode = r, ModeLinux
+		} else {
+			res, err = rs.resolveRelativePath(arg)
+		}
+	case ModeRooted:
+		res, err = rs.resolveRootedPath(arg)
+	case ModeWindows:
+		res, err = rs.resolveWindowsPath(arg)
+		// "C:JunkProjects\\MyRepo" lost only some separators; segment it like a collapsed path instead.
+		if err != nil && len(arg) > 2 && !isSep(arg[2]) {
+			if r, cerr := rs.resolveWindowsPathCollapsed(arg); cerr == nil {
+				rs.tracef(1, "no segment match; resolved as collapsed path")
+				res, err, mode = r, nil, ModeCollapsed
+			}
+		}
+	case ModeCollapsed:
+		res, err = rs.resolveWindowsPathCollapsed(arg)
+	default:
+		res, err = rs.resolveLinux(arg)
+	}
+	if err != nil {
+		return Resolution{Input: input, Mode: mode}, err
+	}
+	if res.Candidates == nil {
+		res.Candidates = []string{res.Resolved}
+	}
+	if res.Considered == 0 {
+		res.Considered = len(res.Candidates)
+	}
+	res.Input, res.Mode = input, mode
+	if err := rs.checkAccess(res.Resolved); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// unquote removes a matching pair of surrounding quotes, as added by Explorer's "Copy as path".
+// A quote at only one end is left alone, since it may be part of a name.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// detectMode classifies arg by the resolution branch that handles it.
+func detectMode(arg string) string {
+	switch {
+	// Path back into a WSL distro (e.g., \\\\wsl$\\Ubuntu\\home or \\\\wsl.localhost\\Ubuntu\\etc)
+	case IsWSLSharePath(arg):
+		return ModeWSL
+	// UNC path (e.g., \\\\server\\share or //server/share)
+	case IsUNCPath(arg):
+		return ModeUNC
+	// Windows path rooted on the current drive (e.g., \\Windows\\System32)
+	case IsRootedWindowsPath(arg):
+		return ModeRooted
+	// Standard Windows path (e.g., C:\\ or C:/)
+	case IsWindowsPath(arg):
+		return ModeWindows
+	// Collapsed Windows path like "C:FooBarBaz" (shell ate backslashes)
+	case IsCollapsedWindowsPath(arg):
+		return ModeCollapsed
+	// Windows path relative to the cwd (e.g., ..\\..\\Shared)
+	case IsRelativeWindowsPath(arg):
+		return ModeRelative
+	}
+	// Linux path semantics
+	return ModeLinux
+}
@@ -0,0 +1,182 @@
+package wslpath
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mkdirs creates each slash-separated path beneath root, with any missing parents, and returns root.
+func mkdirs(t testing.TB, root string, paths ...string) string {
+	t.Helper()
+	for _, p := range paths {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(p)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestMixedSeparators(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Users/Me/Documents")
+	want := filepath.Join(mnt, "c/Users/Me/Documents")
+	// Every mix of separators after the drive and between the three segments, and with the first one missing.
+	for _, first := range []string{`\`, "/", ""} {
+		for _, second := range []string{`\`, "/"} {
+			for _, third := range []string{`\`, "/"} {
+				in := "C:" + first + "users" + second + "me" + third + "documents"
+				if mode, err := DetectMode(in, Options{}); err != nil || mode != ModeWindows {
+					t.Errorf("DetectMode(%q) = %q, %v; want %q", in, mode, err, ModeWindows)
+				}
+				got, err := ResolveTarget(in, "/", "/", Options{MountRoot: mnt})
+				if err != nil || got != want {
+					t.Errorf("ResolveTarget(%q) = %q, %v; want %q", in, got, err, want)
+				}
+			}
+		}
+	}
+}
+
+func TestQuotedInput(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Users/me/My Documents")
+	lin := mkdirs(t, t.TempDir(), "plain", "  padded  ", `"dq`, "sq'")
+	tests := []struct{ in, want string }{
+		{`"C:\Users\me\My Documents"`, filepath.Join(mnt, "c/Users/me/My Documents")},
+		{`'c:/users/me/my documents'`, filepath.Join(mnt, "c/Users/me/My Documents")},
+		{`  "C:\Users\me"  `, filepath.Join(mnt, "c/Users/me")},
+		{`"plain"`, filepath.Join(lin, "plain")},
+		{`'` + lin + `/plain'`, filepath.Join(lin, "plain")},
+		// Spaces inside the quotes belong to the name.
+		{`'  padded  '`, filepath.Join(lin, "  padded  ")},
+		// A quote at one end only is part of the name.
+		{`"dq`, filepath.Join(lin, `"dq`)},
+		{`sq'`, filepath.Join(lin, "sq'")},
+	}
+	for _, tt := range tests {
+		got, err := ResolveTarget(tt.in, lin, "/", Options{MountRoot: mnt})
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveTarget(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{`"plain'`, `'plain"`} {
+		if got, err := ResolveTarget(in, lin, "/", Options{MountRoot: mnt}); err == nil {
+			t.Errorf("ResolveTarget(%q) = %q; want an error for mismatched quotes", in, got)
+		}
+	}
+}
+
+func TestLineBreaks(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Users/me/Documents")
+	want := filepath.Join(mnt, "c/Users/me/Documents")
+	tests := []struct {
+		in   string
+		join bool // only with Options.JoinLines
+	}{
+		{"C:\\Users\\me\\Documents\r\n", false},
+		{"\"C:\\Users\\me\\Documents\"\r\n", false},
+		{"C:\\Users\\me\\Docu\rments", false},
+		{"\r\nC:\\Us\r\ners\\me\r\n\\Documents\r\n", true},
+		{"C:\\Users\\me\\\nDocuments", true},
+		{"C:/Users/me/\r\nDocuments", true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveTarget(tt.in, "/", "/", Options{MountRoot: mnt, JoinLines: true})
+		if err != nil || got != want {
+			t.Errorf("ResolveTarget(%q) with JoinLines = %q, %v; want %q", tt.in, got, err, want)
+		}
+		got, err = ResolveTarget(tt.in, "/", "/", Options{MountRoot: mnt})
+		if tt.join && err == nil {
+			t.Errorf("ResolveTarget(%q) = %q; want the inner newline to stop it", tt.in, got)
+		} else if !tt.join && (err != nil || got != want) {
+			t.Errorf("ResolveTarget(%q) = %q, %v; want %q", tt.in, got, err, want)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	mnt := mkdirs(t, t.TempDir(), "c/Users/me", "c/Program/src", "c/Projects/src", "d/Users/me", "srv/share")
+	if err := os.WriteFile(filepath.Join(mnt, "c/Users/me/notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(mnt, "nowhere"), filepath.Join(mnt, "c/Users/Gone")); err != nil {
+		t.Fatal(err)
+	}
+	lin := filepath.Join(mnt, "c")
+	tests := []struct {
+		name, in, cwd string
+		any           bool // with ResolveAnyDrive
+		opts          Options
+		want          error
+	}{
+		{name: "windows segment", in: `C:\Users\nobody`, want: ErrNotFound},
+		{name: "unc segment", in: `\\srv\share\nothing`, want: ErrNotFound},
+		{name: "reserved name", in: `C:\Users\CON`, want: ErrNotFound},
+		{name: "linux glob", in: lin + "/Nothing*", want: ErrNotFound},
+		{name: "physical", in: lin + "/nothing/..", opts: Options{Physical: true}, want: ErrNotFound},
+		{name: "any drive", in: `Users\nobody`, any: true, want: ErrNotFound},
+		{name: "linux path", in: lin + "/nothing", want: ErrNotFound},
+		{name: "linux path", in: lin + "/nothing", want: fs.ErrNotExist}, // still, for callers of os-style checks
+		{name: "linux broken symlink", in: lin + "/Users/Gone", want: ErrNotFound},
+		{name: "windows broken symlink", in: `C:\Users\gone`, want: ErrNotFound},
+		{name: "unknown user", in: "~nosuchuser-wslcd/x", want: ErrNotFound},
+		{name: "windows file", in: `C:\Users\me\notes.txt`, want: ErrNotFound}, // segments match directories only
+		{name: "wsl share file", in: `\\wsl$\Ubuntu` + lin + `/Users/me/notes.txt`, want: ErrNotDirectory},
+		{name: "linux file", in: lin + "/Users/me/notes.txt", want: ErrNotDirectory},
+		{name: "drive", in: `Q:\Users`, want: ErrDriveMapping},
+		{name: "unc server", in: `\\nosrv\share`, want: ErrDriveMapping},
+		{name: "unc share", in: `\\srv\noshare`, want: ErrDriveMapping},
+		{name: "rooted off a drive", in: `\Users`, cwd: "/", want: ErrDriveMapping},
+		{name: "mapped drive", in: `M:\Users`, opts: Options{DriveMap: map[byte]string{'m': "/nonexistent"}}, want: ErrDriveMapping},
+		{name: "windows glob", in: `C:\pro*\src`, want: ErrAmbiguous},
+		{name: "linux glob", in: lin + "/Pro*/src", want: ErrAmbiguous},
+		{name: "any drives", in: `users\me`, any: true, want: ErrAmbiguous},
+		{name: "collapsed", in: `C:UsersNobody`, want: ErrUnsegmentable},
+		{name: "too deep", in: `C:\users\me`, opts: Options{MaxDepth: 1}, want: ErrTooDeep},
+		{name: "empty", in: `""`, want: ErrNoTarget},
+		{name: "any drive empty", in: " ", any: true, want: ErrNoTarget},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		opts.MountRoot, opts.UNCRoot = mnt, mnt
+		cwd := tt.cwd
+		if cwd == "" {
+			cwd = lin
+		}
+		var err error
+		if tt.any {
+			_, err = ResolveAnyDrive(tt.in, opts)
+		} else {
+			_, err = ResolveTarget(tt.in, cwd, "/", opts)
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: resolving %q = %v; want errors.Is %v", tt.name, tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestDetectModeUnsetVar(t *testing.T) {
+	opts := Options{LookupEnv: func(name string) (string, bool) {
+		if name == "USERPROFILE" {
+			return `C:\Users\me`, true
+		}
+		return "", false
+	}}
+	tests := []struct{ in, want string }{
+		{`%USERPROFILE%\Docs`, ModeWindows},
+		{`%NOPE%\Docs`, ModeRelative},
+		{`C:\%NOPE%`, ModeWindows},
+		{`\\srv\%NOPE%`, ModeUNC},
+		{"%NOPE%", ModeLinux},
+	}
+	for _, tt := range tests {
+		if mode, err := DetectMode(tt.in, opts); err != nil || mode != tt.want {
+			t.Errorf("DetectMode(%q) = %q, %v; want %q", tt.in, mode, err, tt.want)
+		}
+	}
+	// Resolving still needs the value.
+	if _, err := ResolveTarget(`C:\%NOPE%`, "/", "/", opts); err == nil {
+		t.Errorf("ResolveTarget(C:\\%%NOPE%%) succeeded; want an unset variable error")
+	}
+}
@@ -0,0 +1,22 @@
+package wslpath
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// resolveViaWslpath asks the wslpath utility named by Options.Wslpath for the Linux form of win.
+// The result is not checked against the filesystem.
+func (rs *resolver) resolveViaWslpath(win string) (string, error) {
+	out, err := exec.Command(rs.opts.Wslpath, "-u", win).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s -u %s: %v", rs.opts.Wslpath, win, err)
+	}
+	p := strings.TrimSuffix(string(out), "\n")
+	if p == "" {
+		return "", fmt.Errorf("%s -u %s: no output", rs.opts.Wslpath, win)
+	}
+	rs.tracef(1, "wslpath mapped %s to %s", win, p)
+	return p, nil
+}